@@ -0,0 +1,274 @@
+package dynamicsamplingprocessor
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/collector/component"
+	"go.uber.org/zap"
+)
+
+const (
+	// defaultBudgetAdjustmentInterval is used when the budget adjustment_interval is not set.
+	defaultBudgetAdjustmentInterval = time.Minute
+
+	// maxBudgetStep bounds the factor the goal changes by in one adjustment, so that a short burst or lull does
+	// not swing the goal.
+	maxBudgetStep = 2.0
+
+	// minBudgetScale bounds how far the goal is scaled from the configured goal.
+	minBudgetScale = 1e-6
+)
+
+// UsageSource is implemented by extensions that count the events the collector sends, such as the honeycomb
+// extension. The dynamic sampler adjusts its goal to keep the events of the signals it samples within an event
+// budget.
+type UsageSource interface {
+	// RecordedSpans returns the number of spans recorded since the extension was created.
+	RecordedSpans() int64
+
+	// RecordedLogRecords returns the number of log records recorded since the extension was created.
+	RecordedLogRecords() int64
+}
+
+// budgetControllers holds the budget controller of each component ID, shared by its logs and traces processors so
+// that they split one budget rather than each correcting for the same usage.
+var budgetControllers = newSharedComponents[*budgetController]()
+
+// goalSetter is implemented by the deciders of the logs and traces processors.
+type goalSetter interface {
+	setGoals(goals map[string]Goals) error
+}
+
+// budgetSignal is a signal whose goals a budget controller adjusts, and whose recorded events count towards the
+// budget.
+type budgetSignal struct {
+	decider  goalSetter
+	recorded func(UsageSource) int64
+
+	// last is the number of events of the signal recorded at the last adjustment.
+	last int64
+}
+
+// budgetController adjusts the goal of the default sampler to keep the events recorded by a UsageSource within an
+// event budget. At every adjustment, the rate at which events were recorded since the last adjustment is compared
+// with the rate the rest of the budget allows for the rest of the period, and the goal is scaled by their ratio. The
+// goal is never relaxed past the configured goal. Only the events of the attached signals are counted, and all of
+// them get the same goal.
+type budgetController struct {
+	sourceID *component.ID
+	events   int64
+	period   BudgetPeriod
+	interval time.Duration
+	base     Goals
+	logger   *zap.Logger
+
+	// mu guards the fields below, which change as signals are attached and detached and the goals adjusted.
+	mu      sync.Mutex
+	source  UsageSource
+	signals []*budgetSignal
+
+	// scale is the factor the configured throughput goal is multiplied by, and the configured sample rate goal is
+	// divided by. It is at most 1.
+	scale float64
+	goals Goals
+
+	// periodStart is the start of the current period, and used the number of events recorded in it.
+	periodStart time.Time
+	used        int64
+
+	lastAdjusted time.Time
+
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+func newBudgetController(cfg *Config, logger *zap.Logger) *budgetController {
+	period := cfg.Budget.Period
+	if period == "" {
+		period = BudgetPeriodMonthly
+	}
+	interval := cfg.Budget.AdjustmentInterval
+	if interval == 0 {
+		interval = defaultBudgetAdjustmentInterval
+	}
+	return &budgetController{
+		sourceID: cfg.Budget.UsageSourceID,
+		events:   cfg.Budget.Events,
+		period:   period,
+		interval: interval,
+		base:     cfg.SamplerConfig.goals(),
+		logger:   logger,
+	}
+}
+
+// attach looks up the usage source and starts adjusting the goals of d, counting the events that recorded returns
+// for its signal. The first signal attached starts adjusting the goals periodically. It does nothing when no usage
+// source is configured.
+func (b *budgetController) attach(host component.Host, d goalSetter, recorded func(UsageSource) int64) error {
+	if b.sourceID == nil {
+		return nil
+	}
+
+	ext, ok := host.GetExtensions()[*b.sourceID]
+	if !ok {
+		return fmt.Errorf("usage source extension %q not found", b.sourceID)
+	}
+	source, ok := ext.(UsageSource)
+	if !ok {
+		return fmt.Errorf("extension %q does not implement UsageSource", b.sourceID)
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if len(b.signals) == 0 {
+		b.reset(source, time.Now())
+	}
+	if err := b.add(d, recorded); err != nil {
+		return err
+	}
+	if b.done == nil {
+		b.done = make(chan struct{})
+		b.wg.Add(1)
+		go b.adjustLoop(b.done)
+	}
+	return nil
+}
+
+// detach stops adjusting the goals of d. Adjusting stops once no signal is attached.
+func (b *budgetController) detach(d goalSetter) {
+	b.mu.Lock()
+	b.signals = slices.DeleteFunc(b.signals, func(s *budgetSignal) bool { return s.decider == d })
+	var done chan struct{}
+	if len(b.signals) == 0 {
+		done, b.done = b.done, nil
+	}
+	b.mu.Unlock()
+
+	if done != nil {
+		close(done)
+		b.wg.Wait()
+	}
+}
+
+// reset starts tracking the usage recorded by source from now. The usage recorded before now in the current period
+// is not known, so the budget is assumed to have been used evenly until now. The caller must hold b.mu.
+func (b *budgetController) reset(source UsageSource, now time.Time) {
+	b.source = source
+	b.scale = 1
+	b.goals = b.base
+	b.periodStart = b.startOfPeriod(now)
+	elapsed := now.Sub(b.periodStart).Seconds() / b.endOfPeriod(b.periodStart).Sub(b.periodStart).Seconds()
+	b.used = int64(float64(b.events) * elapsed)
+	b.lastAdjusted = now
+}
+
+// add counts the events recorded for a signal from now on, and gives d the goals the budget currently allows. The
+// caller must hold b.mu.
+func (b *budgetController) add(d goalSetter, recorded func(UsageSource) int64) error {
+	if b.goals != b.base {
+		if err := d.setGoals(map[string]Goals{defaultSamplerName: b.goals}); err != nil {
+			return err
+		}
+	}
+	b.signals = append(b.signals, &budgetSignal{decider: d, recorded: recorded, last: recorded(b.source)})
+	return nil
+}
+
+func (b *budgetController) adjustLoop(done chan struct{}) {
+	defer b.wg.Done()
+
+	ticker := time.NewTicker(b.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case now := <-ticker.C:
+			b.mu.Lock()
+			err := b.adjust(now)
+			b.mu.Unlock()
+			if err != nil {
+				b.logger.Warn("failed to adjust dynamic sampler goals to the budget", zap.Error(err))
+			}
+		case <-done:
+			return
+		}
+	}
+}
+
+// adjust counts the events recorded since the last adjustment towards the budget and scales the goal by the ratio
+// of the rate the rest of the budget allows to the rate events were recorded at. The caller must hold b.mu.
+func (b *budgetController) adjust(now time.Time) error {
+	var delta int64
+	for _, s := range b.signals {
+		recorded := s.recorded(b.source)
+		delta += recorded - s.last
+		s.last = recorded
+	}
+	elapsed := now.Sub(b.lastAdjusted).Seconds()
+	b.lastAdjusted = now
+
+	if start := b.startOfPeriod(now); !start.Equal(b.periodStart) {
+		b.periodStart = start
+		b.used = 0
+	}
+	b.used += delta
+	if delta <= 0 || elapsed <= 0 {
+		return nil
+	}
+
+	remaining := b.endOfPeriod(b.periodStart).Sub(now).Seconds()
+	allowed := float64(max(b.events-b.used, 0)) / remaining
+	observed := float64(delta) / elapsed
+	step := min(max(allowed/observed, 1/maxBudgetStep), maxBudgetStep)
+	b.scale = min(max(b.scale*step, minBudgetScale), 1)
+
+	goals := b.base.scaled(b.scale)
+	b.logger.Debug("adjusting dynamic sampler goals to the budget",
+		zap.Int64("budget_used", b.used),
+		zap.Float64("allowed_events_per_second", allowed),
+		zap.Float64("recorded_events_per_second", observed),
+		zap.Float64("scale", b.scale))
+	if goals == b.goals {
+		return nil
+	}
+	var errs error
+	for _, s := range b.signals {
+		errs = errors.Join(errs, s.decider.setGoals(map[string]Goals{defaultSamplerName: goals}))
+	}
+	if errs != nil {
+		return errs
+	}
+	b.goals = goals
+	return nil
+}
+
+// startOfPeriod returns the start of the period that now is in.
+func (b *budgetController) startOfPeriod(now time.Time) time.Time {
+	now = now.UTC()
+	if b.period == BudgetPeriodDaily {
+		return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	}
+	return time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// endOfPeriod returns the end of the period that starts at start.
+func (b *budgetController) endOfPeriod(start time.Time) time.Time {
+	if b.period == BudgetPeriodDaily {
+		return start.AddDate(0, 0, 1)
+	}
+	return start.AddDate(0, 1, 0)
+}
+
+// scaled returns the goals with the throughput goal multiplied by scale and the sample rate goal divided by it.
+func (g Goals) scaled(scale float64) Goals {
+	if g.GoalSampleRate > 0 {
+		g.GoalSampleRate = int(min(math.Ceil(float64(g.GoalSampleRate)/scale), math.MaxInt32))
+	}
+	g.GoalThroughputPerSecond *= scale
+	return g
+}
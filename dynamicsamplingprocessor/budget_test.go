@@ -0,0 +1,332 @@
+package dynamicsamplingprocessor
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/open-telemetry/opentelemetry-collector-contrib/pkg/ottl/contexts/ottllog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/collector/consumer/consumertest"
+	"go.opentelemetry.io/collector/processor/processortest"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/noop"
+	"go.uber.org/zap"
+)
+
+// testUsageSource is a UsageSource extension with settable span and log record counts.
+type testUsageSource struct {
+	component.StartFunc
+	component.ShutdownFunc
+	spans      atomic.Int64
+	logRecords atomic.Int64
+}
+
+func (s *testUsageSource) RecordedSpans() int64 {
+	return s.spans.Load()
+}
+
+func (s *testUsageSource) RecordedLogRecords() int64 {
+	return s.logRecords.Load()
+}
+
+// newTestBudgetController returns a budget controller adjusting the goals of a log decider, whose events are the
+// log records of the returned usage source.
+func newTestBudgetController(t *testing.T, cfg *Config, now time.Time) (*budgetController, *decider[ottllog.TransformContext], *testUsageSource) {
+	sourceID := component.MustNewID("honeycomb")
+	cfg.Budget.UsageSourceID = &sourceID
+	require.NoError(t, cfg.Validate())
+
+	source := &testUsageSource{}
+	d := newTestLogDecider(t, cfg)
+	b := newBudgetController(cfg, zap.NewNop())
+	b.reset(source, now)
+	require.NoError(t, b.add(d, UsageSource.RecordedLogRecords))
+	return b, d, source
+}
+
+func TestBudgetControllerSampleRateGoal(t *testing.T) {
+	cfg := createDefaultConfig().(*Config)
+	cfg.KeyFields = []string{"key1"}
+	cfg.GoalSampleRate = 10
+	// one event per second
+	cfg.Budget = BudgetConfig{Events: 86400, Period: BudgetPeriodDaily}
+
+	now := time.Date(2026, time.October, 18, 12, 0, 0, 0, time.UTC)
+	b, d, source := newTestBudgetController(t, cfg, now)
+	assert.Equal(t, int64(43200), b.used, "half of the budget is assumed to be used by midday")
+
+	// ten events per second are far over budget, but the goal changes by at most maxBudgetStep at a time
+	now = now.Add(time.Minute)
+	source.logRecords.Add(600)
+	require.NoError(t, b.adjust(now))
+	assert.Equal(t, 20, d.sampler.cfg.GoalSampleRate)
+
+	now = now.Add(time.Minute)
+	source.logRecords.Add(600)
+	require.NoError(t, b.adjust(now))
+	assert.Equal(t, 40, d.sampler.cfg.GoalSampleRate)
+
+	// no events to go by
+	now = now.Add(time.Minute)
+	require.NoError(t, b.adjust(now))
+	assert.Equal(t, 40, d.sampler.cfg.GoalSampleRate)
+
+	// with headroom the goal is relaxed, but not past the configured goal
+	for i := 0; i < 10; i++ {
+		now = now.Add(time.Minute)
+		source.logRecords.Add(6)
+		require.NoError(t, b.adjust(now))
+	}
+	assert.Equal(t, 10, d.sampler.cfg.GoalSampleRate)
+	assert.Equal(t, 1.0, b.scale)
+}
+
+func TestBudgetControllerThroughputGoal(t *testing.T) {
+	cfg := createDefaultConfig().(*Config)
+	cfg.Sampler = EMAThroughputSampler
+	cfg.KeyFields = []string{"key1"}
+	cfg.GoalThroughputPerSecond = 100
+	cfg.Budget = BudgetConfig{Events: 86400, Period: BudgetPeriodDaily}
+
+	now := time.Date(2026, time.October, 18, 12, 0, 0, 0, time.UTC)
+	b, d, source := newTestBudgetController(t, cfg, now)
+
+	now = now.Add(time.Minute)
+	source.logRecords.Add(90)
+	require.NoError(t, b.adjust(now))
+	assert.Equal(t, 66, d.sampler.cfg.GoalThroughputPerSecond)
+}
+
+func TestBudgetControllerPreSamplingUsage(t *testing.T) {
+	cfg := createDefaultConfig().(*Config)
+	cfg.KeyFields = []string{"key1"}
+	cfg.GoalSampleRate = 10
+	// one event per second
+	cfg.Budget = BudgetConfig{Events: 86400, Period: BudgetPeriodDaily}
+
+	now := time.Date(2026, time.October, 18, 12, 0, 0, 0, time.UTC)
+	b, d, source := newTestBudgetController(t, cfg, now)
+
+	// a usage source placed before the sampler counts the same ten events per second whatever the goal, so every
+	// adjustment tightens the goal by the most one step allows and the scale never settles
+	for i := 1; i <= 10; i++ {
+		now = now.Add(time.Minute)
+		source.logRecords.Add(600)
+		require.NoError(t, b.adjust(now))
+		assert.Equal(t, math.Pow(1/maxBudgetStep, float64(i)), b.scale, "adjustment %d", i)
+	}
+	assert.Equal(t, 10240, d.sampler.cfg.GoalSampleRate)
+}
+
+func TestBudgetControllerSharedBetweenSignals(t *testing.T) {
+	cfg := createDefaultConfig().(*Config)
+	cfg.KeyFields = []string{"key1"}
+	cfg.GoalSampleRate = 10
+	// one event per second
+	cfg.Budget = BudgetConfig{Events: 86400, Period: BudgetPeriodDaily}
+
+	now := time.Date(2026, time.October, 18, 12, 0, 0, 0, time.UTC)
+	b, logs, source := newTestBudgetController(t, cfg, now)
+
+	set := componenttest.NewNopTelemetrySettings()
+	parser, err := newSpanParser(set)
+	require.NoError(t, err)
+	traces, err := newDecider(cfg, parser, set)
+	require.NoError(t, err)
+	require.NoError(t, b.add(traces, UsageSource.RecordedSpans))
+
+	// spans and log records each use up half of the budget, and both signals get the same goal
+	now = now.Add(time.Minute)
+	source.spans.Add(30)
+	source.logRecords.Add(30)
+	require.NoError(t, b.adjust(now))
+	assert.Equal(t, 10, logs.sampler.cfg.GoalSampleRate)
+	assert.Equal(t, 10, traces.sampler.cfg.GoalSampleRate)
+
+	// ten events per second over both signals are over budget, even though neither is on its own
+	now = now.Add(time.Minute)
+	source.spans.Add(300)
+	source.logRecords.Add(300)
+	require.NoError(t, b.adjust(now))
+	assert.Equal(t, 20, logs.sampler.cfg.GoalSampleRate)
+	assert.Equal(t, 20, traces.sampler.cfg.GoalSampleRate)
+
+	// once the traces processor is gone, only log records count
+	b.detach(traces)
+	now = now.Add(time.Minute)
+	source.spans.Add(6000)
+	require.NoError(t, b.adjust(now))
+	assert.Equal(t, int64(43200+660), b.used)
+	assert.Equal(t, 20, logs.sampler.cfg.GoalSampleRate)
+}
+
+func TestBudgetControllerPeriods(t *testing.T) {
+	cfg := createDefaultConfig().(*Config)
+	cfg.KeyFields = []string{"key1"}
+	cfg.Budget = BudgetConfig{Events: 1000}
+
+	now := time.Date(2026, time.October, 31, 23, 59, 0, 0, time.UTC)
+	b, _, source := newTestBudgetController(t, cfg, now)
+	assert.Equal(t, BudgetPeriodMonthly, b.period)
+	assert.Equal(t, time.Date(2026, time.October, 1, 0, 0, 0, 0, time.UTC), b.periodStart)
+
+	// the events recorded since the last adjustment count towards the new period
+	now = now.Add(2 * time.Minute)
+	source.logRecords.Add(5)
+	require.NoError(t, b.adjust(now))
+	assert.Equal(t, time.Date(2026, time.November, 1, 0, 0, 0, 0, time.UTC), b.periodStart)
+	assert.Equal(t, int64(5), b.used)
+	assert.Equal(t, time.Date(2026, time.December, 1, 0, 0, 0, 0, time.UTC), b.endOfPeriod(b.periodStart))
+}
+
+func TestGoalsScaled(t *testing.T) {
+	assert.Equal(t, Goals{GoalSampleRate: 34}, Goals{GoalSampleRate: 10}.scaled(0.3))
+	assert.InDelta(t, 30, Goals{GoalThroughputPerSecond: 100}.scaled(0.3).GoalThroughputPerSecond, 1e-9)
+	assert.InDelta(t, 0.25, Goals{GoalThroughputPerSecond: 0.5}.scaled(0.5).GoalThroughputPerSecond, 1e-9)
+
+	// samplers with a whole number goal are not scaled below 1, while WindowedThroughputSampler keeps the fraction
+	scaled := Goals{GoalThroughputPerSecond: 100}.scaled(minBudgetScale)
+	emaThroughput := SamplerConfig{Sampler: EMAThroughputSampler}.withGoal(scaled.GoalSampleRate, scaled.GoalThroughputPerSecond)
+	assert.Equal(t, 1, emaThroughput.GoalThroughputPerSecond)
+	windowed := SamplerConfig{Sampler: WindowedThroughputSampler}.withGoal(scaled.GoalSampleRate, scaled.GoalThroughputPerSecond)
+	assert.InDelta(t, 1e-4, windowed.WindowedThroughput.GoalThroughputPerSecond, 1e-12)
+}
+
+func TestBudgetFractionalGoal(t *testing.T) {
+	cfg := createDefaultConfig().(*Config)
+	cfg.Sampler = WindowedThroughputSampler
+	cfg.WindowedThroughput = WindowedThroughputConfig{GoalThroughputPerSecond: 0.5}
+	cfg.Budget = BudgetConfig{Events: 1000}
+
+	now := time.Date(2026, time.October, 1, 0, 0, 0, 0, time.UTC)
+	b, d, source := newTestBudgetController(t, cfg, now)
+	assert.Equal(t, Goals{GoalThroughputPerSecond: 0.5}, b.base)
+
+	// recording far more than the budget allows lowers the goal without losing it to rounding
+	now = now.Add(time.Minute)
+	source.logRecords.Add(1000)
+	require.NoError(t, b.adjust(now))
+	assert.InDelta(t, 0.25, b.goals.GoalThroughputPerSecond, 1e-9)
+	assert.InDelta(t, 0.25, d.samplers[0].config().WindowedThroughput.GoalThroughputPerSecond, 1e-9)
+}
+
+func TestProcessorBudget(t *testing.T) {
+	sourceID := component.MustNewID("honeycomb")
+	cfg := createDefaultConfig().(*Config)
+	cfg.KeyFields = []string{"key1"}
+	cfg.Budget = BudgetConfig{UsageSourceID: &sourceID, Events: 1000000}
+
+	tests := []struct {
+		name       string
+		extensions map[component.ID]component.Component
+		expected   string
+	}{
+		{
+			name:       "usage source",
+			extensions: map[component.ID]component.Component{sourceID: &testUsageSource{}},
+		},
+		{
+			name:     "missing extension",
+			expected: `usage source extension "honeycomb" not found`,
+		},
+		{
+			name:       "extension without UsageSource",
+			extensions: map[component.ID]component.Component{sourceID: &memoryStorage{}},
+			expected:   `extension "honeycomb" does not implement UsageSource`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tp, err := NewFactory().CreateTraces(context.Background(), processortest.NewNopSettings(typ), cfg, consumertest.NewNop())
+			require.NoError(t, err)
+			host := storageHost{Host: componenttest.NewNopHost(), extensions: tt.extensions}
+			err = tp.Start(context.Background(), host)
+			if tt.expected == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tt.expected)
+			}
+			require.NoError(t, tp.Shutdown(context.Background()))
+		})
+	}
+}
+
+func TestProcessorBudgetSharedBetweenPipelines(t *testing.T) {
+	sourceID := component.MustNewID("honeycomb")
+	cfg := createDefaultConfig().(*Config)
+	cfg.KeyFields = []string{"key1"}
+	cfg.Budget = BudgetConfig{UsageSourceID: &sourceID, Events: 1000000}
+
+	set := processortest.NewNopSettings(typ)
+	lp, err := NewFactory().CreateLogs(context.Background(), set, cfg, consumertest.NewNop())
+	require.NoError(t, err)
+	tp, err := NewFactory().CreateTraces(context.Background(), set, cfg, consumertest.NewNop())
+	require.NoError(t, err)
+
+	host := storageHost{Host: componenttest.NewNopHost(), extensions: map[component.ID]component.Component{sourceID: &testUsageSource{}}}
+	require.NoError(t, lp.Start(context.Background(), host))
+	require.NoError(t, tp.Start(context.Background(), host))
+
+	// one controller counts the events of both pipelines
+	require.Contains(t, budgetControllers.entries, set.ID)
+	b := budgetControllers.entries[set.ID].value
+	b.mu.Lock()
+	assert.Len(t, b.signals, 2)
+	b.mu.Unlock()
+
+	require.NoError(t, lp.Shutdown(context.Background()))
+	b.mu.Lock()
+	assert.Len(t, b.signals, 1)
+	b.mu.Unlock()
+
+	require.NoError(t, tp.Shutdown(context.Background()))
+	assert.NotContains(t, budgetControllers.entries, set.ID)
+}
+
+// failingMeterProvider fails to create the counter with the given name, so that creating a processor fails after
+// the processor has acquired its shared budget and debug server.
+type failingMeterProvider struct {
+	noop.MeterProvider
+	counter string
+}
+
+func (p failingMeterProvider) Meter(string, ...metric.MeterOption) metric.Meter {
+	return failingMeter{counter: p.counter}
+}
+
+type failingMeter struct {
+	noop.Meter
+	counter string
+}
+
+func (m failingMeter) Int64Counter(name string, options ...metric.Int64CounterOption) (metric.Int64Counter, error) {
+	if name == m.counter {
+		return nil, errors.New("failed to create counter")
+	}
+	return m.Meter.Int64Counter(name, options...)
+}
+
+func TestProcessorCreateFailureReleasesSharedComponents(t *testing.T) {
+	set := processortest.NewNopSettings(typ)
+	set.MeterProvider = failingMeterProvider{counter: "otelcol_processor_incoming_items"}
+	cfg := createDefaultConfig().(*Config)
+
+	_, err := NewFactory().CreateLogs(context.Background(), set, cfg, consumertest.NewNop())
+	require.Error(t, err)
+	_, err = NewFactory().CreateTraces(context.Background(), set, cfg, consumertest.NewNop())
+	require.Error(t, err)
+
+	budgetControllers.mu.Lock()
+	assert.NotContains(t, budgetControllers.entries, set.ID)
+	budgetControllers.mu.Unlock()
+	debugServers.mu.Lock()
+	assert.NotContains(t, debugServers.entries, set.ID)
+	debugServers.mu.Unlock()
+}
@@ -0,0 +1,103 @@
+package dynamicsamplingprocessor
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/open-telemetry/opentelemetry-collector-contrib/pkg/ottl"
+	"go.opentelemetry.io/collector/component"
+)
+
+// sampleRateClamp limits the sample rates of the records it matches. A zero limit leaves that side to the global
+// limit.
+type sampleRateClamp[K any] struct {
+	key           string
+	conditions    *ottl.ConditionSequence[K]
+	minSampleRate int
+	maxSampleRate int
+}
+
+// sampleRateClamps limits the sample rates computed by the samplers to the first matching clamp, or to the global
+// floor and ceiling for records that match no clamp.
+type sampleRateClamps[K any] struct {
+	clamps        []sampleRateClamp[K]
+	minSampleRate int
+	maxSampleRate int
+}
+
+// newSampleRateClamps parses the clamps in the config using the given OTTL parser. It returns nil when no limit is
+// configured.
+func newSampleRateClamps[K any](cfg *Config, parser ottl.Parser[K], set component.TelemetrySettings) (*sampleRateClamps[K], error) {
+	if cfg.MinSampleRate == 0 && cfg.MaxSampleRate == 0 && len(cfg.SampleRateClamps) == 0 {
+		return nil, nil
+	}
+
+	c := &sampleRateClamps[K]{
+		clamps:        make([]sampleRateClamp[K], 0, len(cfg.SampleRateClamps)),
+		minSampleRate: cfg.MinSampleRate,
+		maxSampleRate: cfg.MaxSampleRate,
+	}
+	for i, clampCfg := range cfg.SampleRateClamps {
+		clamp := sampleRateClamp[K]{
+			key:           clampCfg.Key,
+			minSampleRate: clampCfg.MinSampleRate,
+			maxSampleRate: clampCfg.MaxSampleRate,
+		}
+		if len(clampCfg.Conditions) > 0 {
+			conditions, err := parser.ParseConditions(clampCfg.Conditions)
+			if err != nil {
+				return nil, fmt.Errorf("sample rate clamp %d: %w", i, err)
+			}
+			sequence := ottl.NewConditionSequence(conditions, set,
+				ottl.WithLogicOperation[K](ottl.And),
+				ottl.WithConditionSequenceErrorMode[K](ottl.IgnoreError))
+			clamp.conditions = &sequence
+		}
+		c.clamps = append(c.clamps, clamp)
+	}
+	return c, nil
+}
+
+// apply returns sampleRate, computed for key, raised to the minimum or lowered to the maximum sample rate of the
+// first clamp that matches the record described by tCtx. The global limits apply to the sides the clamp leaves
+// unset, and to records that match no clamp.
+func (c *sampleRateClamps[K]) apply(ctx context.Context, tCtx K, key string, sampleRate int) int {
+	if c == nil {
+		return sampleRate
+	}
+
+	minSampleRate, maxSampleRate := c.minSampleRate, c.maxSampleRate
+	for _, clamp := range c.clamps {
+		if !clamp.matches(ctx, tCtx, key) {
+			continue
+		}
+		if clamp.minSampleRate > 0 {
+			minSampleRate = clamp.minSampleRate
+		}
+		if clamp.maxSampleRate > 0 {
+			maxSampleRate = clamp.maxSampleRate
+		}
+		break
+	}
+
+	if minSampleRate > 0 && sampleRate < minSampleRate {
+		return minSampleRate
+	}
+	if maxSampleRate > 0 && sampleRate > maxSampleRate {
+		return maxSampleRate
+	}
+	return sampleRate
+}
+
+// matches reports whether the record described by tCtx has the key of the clamp, if it has one, and meets all its
+// conditions. Conditions that fail to evaluate do not match.
+func (c *sampleRateClamp[K]) matches(ctx context.Context, tCtx K, key string) bool {
+	if c.key != "" && c.key != key {
+		return false
+	}
+	if c.conditions == nil {
+		return true
+	}
+	match, err := c.conditions.Eval(ctx, tCtx)
+	return err == nil && match
+}
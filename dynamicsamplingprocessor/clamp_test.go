@@ -0,0 +1,74 @@
+package dynamicsamplingprocessor
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/consumer/consumertest"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/processor/processortest"
+)
+
+func TestSampleRateClamps(t *testing.T) {
+	cfg := createDefaultConfig().(*Config)
+	cfg.Sampler = StaticSampler
+	cfg.KeyFields = []string{"service.name"}
+	cfg.Static = StaticConfig{
+		Default: 3,
+		Rates:   map[string]int{"payments": 20, "frontend": 10, "batch": 80},
+	}
+	cfg.MinSampleRate = 4
+	cfg.MaxSampleRate = 50
+	cfg.SampleRateClamps = []SampleRateClampConfig{
+		{Key: "payments", MaxSampleRate: 5},
+		{Conditions: []string{`attributes["http.route"] == "/health"`}, MinSampleRate: 100},
+		{Key: "batch", MinSampleRate: 10},
+	}
+	require.NoError(t, cfg.Validate())
+	d := newTestLogDecider(t, cfg)
+
+	tests := []struct {
+		name     string
+		service  string
+		route    string
+		expected int
+	}{
+		{name: "key clamp lowers rate", service: "payments", expected: 5},
+		{name: "condition clamp raises rate", service: "frontend", route: "/health", expected: 100},
+		{name: "first matching clamp applies", service: "payments", route: "/health", expected: 5},
+		{name: "global ceiling applies to side the clamp leaves unset", service: "batch", expected: 50},
+		{name: "rate within global limits", service: "frontend", expected: 10},
+		{name: "global floor", service: "search", expected: 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dec := decideLog(d, func(lr plog.LogRecord) {
+				lr.Attributes().PutStr("service.name", tt.service)
+				if tt.route != "" {
+					lr.Attributes().PutStr("http.route", tt.route)
+				}
+			})
+			assert.Equal(t, tt.expected, dec.sampleRate)
+		})
+	}
+}
+
+func TestSampleRateClampsNotAppliedToRules(t *testing.T) {
+	cfg := createDefaultConfig().(*Config)
+	cfg.MaxSampleRate = 5
+	cfg.Rules = []RuleConfig{{Name: "all", Action: RuleActionSampleRate, SampleRate: 50}}
+	require.NoError(t, cfg.Validate())
+	d := newTestLogDecider(t, cfg)
+
+	assert.Equal(t, 50, decideLog(d, func(plog.LogRecord) {}).sampleRate)
+}
+
+func TestInvalidSampleRateClampCondition(t *testing.T) {
+	cfg := createDefaultConfig().(*Config)
+	cfg.SampleRateClamps = []SampleRateClampConfig{{Conditions: []string{`NotAFunction()`}, MinSampleRate: 2}}
+
+	_, err := NewFactory().CreateLogs(context.Background(), processortest.NewNopSettings(typ), cfg, consumertest.NewNop())
+	assert.ErrorContains(t, err, "sample rate clamp 0:")
+}
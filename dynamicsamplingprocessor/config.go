@@ -1,7 +1,13 @@
 package dynamicsamplingprocessor
 
 import (
+	"errors"
 	"fmt"
+	"math"
+	"net"
+	"slices"
+	"strings"
+	"time"
 
 	"go.opentelemetry.io/collector/component"
 )
@@ -9,43 +15,992 @@ import (
 type SamplerType string
 
 const (
-	EMADynamicSampler    string = "EMADynamicSampler"
-	EMAThroughputSampler string = "EMAThroughputSampler"
+	EMADynamicSampler         string = "EMADynamicSampler"
+	EMAThroughputSampler      string = "EMAThroughputSampler"
+	AvgSampleRateSampler      string = "AvgSampleRateSampler"
+	AvgSampleWithMinSampler   string = "AvgSampleWithMinSampler"
+	TotalThroughputSampler    string = "TotalThroughputSampler"
+	PerKeyThroughputSampler   string = "PerKeyThroughputSampler"
+	OnlyOnceSampler           string = "OnlyOnceSampler"
+	StaticSampler             string = "StaticSampler"
+	WindowedThroughputSampler string = "WindowedThroughputSampler"
 )
 
+// validSamplers lists every sampler name accepted by the sampler config option.
+var validSamplers = []string{
+	EMADynamicSampler,
+	EMAThroughputSampler,
+	AvgSampleRateSampler,
+	AvgSampleWithMinSampler,
+	TotalThroughputSampler,
+	PerKeyThroughputSampler,
+	OnlyOnceSampler,
+	StaticSampler,
+	WindowedThroughputSampler,
+}
+
 type Config struct {
+	// SamplerConfig is the default sampler, used for records that match no rule.
+	SamplerConfig `mapstructure:",squash"`
+
+	// Rules is an ordered list of sampling rules. Each record is checked against the rules in order and the
+	// first matching rule decides how the record is sampled. Records that match no rule are sampled by the
+	// default sampler.
+	Rules []RuleConfig `mapstructure:"rules"`
+
+	// Samplers is a set of named samplers that rules can hand records off to.
+	Samplers map[string]SamplerConfig `mapstructure:"samplers"`
+
+	// MinSampleRate and MaxSampleRate are the floor and ceiling of the sample rates computed by the samplers. Rates
+	// outside them are raised or lowered to fit. Rules with a fixed sample rate are not limited. Default is 0 (no
+	// limit).
+	MinSampleRate int `mapstructure:"min_sample_rate"`
+	MaxSampleRate int `mapstructure:"max_sample_rate"`
+
+	// SampleRateClamps limits the sample rates computed for individual keys, in place of MinSampleRate and
+	// MaxSampleRate. The first clamp that matches a record applies.
+	SampleRateClamps []SampleRateClampConfig `mapstructure:"sample_rate_clamps"`
+
+	// SampleRateAttribute is the record attribute the applied sample rate is written to. A rate already present in
+	// the attribute, for example from an SDK or an earlier sampler, is multiplied into the written rate. Default is
+	// SampleRate.
+	SampleRateAttribute string `mapstructure:"sample_rate_attribute"`
+
+	// WeightByUpstreamSampleRate counts each record as the number of events given by its incoming sample rate
+	// when updating the sampler, so the sampler sees the traffic the records stand for.
+	WeightByUpstreamSampleRate bool `mapstructure:"weight_by_upstream_sample_rate"`
+
+	// ThroughputUnit is what the samplers count for each record. With bytes, each record counts as its size in
+	// the OTLP protobuf encoding, so throughput goals are in bytes per second. Default is records.
+	ThroughputUnit ThroughputUnit `mapstructure:"throughput_unit"`
+
+	// ProbabilitySampling makes trace sampling decisions with OpenTelemetry consistent probability sampling and
+	// records the sampling threshold of kept spans in the th value of their tracestate.
+	ProbabilitySampling bool `mapstructure:"probability_sampling"`
+
+	// TailSampling buffers the spans of each trace and makes the sampling decision for the whole trace once it has
+	// been buffered for a decision wait, instead of for the spans of each trace in every batch. It has no effect on
+	// logs.
+	TailSampling TailSamplingConfig `mapstructure:"tail_sampling"`
+
+	// StorageID is the ID of a storage extension, such as file_storage, used to persist the state of the samplers
+	// across collector restarts. State is not persisted when it is not set.
+	StorageID *component.ID `mapstructure:"storage"`
+
+	// StateSnapshotInterval is how often the state of the samplers is saved to the storage extension. State is also
+	// saved when the processor shuts down. Default is 1m.
+	StateSnapshotInterval time.Duration `mapstructure:"state_snapshot_interval"`
+
+	// GoalSourceID is the ID of an extension implementing GoalSource, such as one driven by OpAMP remote
+	// configuration, that changes the goals of the samplers at runtime.
+	GoalSourceID *component.ID `mapstructure:"goal_source"`
+
+	// Budget adjusts the goal of the default sampler to keep the events recorded by a usage source, such as the
+	// honeycomb extension, within an event budget.
+	Budget BudgetConfig `mapstructure:"budget"`
+
+	// Schedules override the goal of the default sampler during recurring time windows. When several windows are
+	// open, the first schedule in the list wins.
+	Schedules []ScheduleConfig `mapstructure:"schedules"`
+
+	// DryRun keeps every record and annotates it with the sampling decision instead of applying it, in the
+	// sampler.kept, sampler.sample_rate and sampler.key attributes. The sample rate attribute is left unchanged.
+	DryRun bool `mapstructure:"dry_run"`
+
+	// Deterministic derives sampling decisions from a hash of a field instead of choosing at random.
+	Deterministic DeterministicConfig `mapstructure:"deterministic"`
+
+	// Summaries emits a log record per key at every interval with the number of log records seen, kept and
+	// dropped, so that what was sampled away is known downstream.
+	Summaries SummariesConfig `mapstructure:"summaries"`
+
+	// LogTemplates configures how log bodies are clustered into the templates used by the body_template field.
+	LogTemplates LogTemplatesConfig `mapstructure:"log_templates"`
+
+	// Debug configures an HTTP page showing the live state of the samplers.
+	Debug DebugConfig `mapstructure:"debug"`
+}
+
+// DebugConfig configures the debug page of the processor.
+type DebugConfig struct {
+	// Endpoint is the host:port the debug page is served on, such as localhost:55680. The page is not served when it
+	// is not set.
+	Endpoint string `mapstructure:"endpoint"`
+}
+
+func (cfg *DebugConfig) validate() error {
+	if cfg.Endpoint == "" {
+		return nil
+	}
+	if _, _, err := net.SplitHostPort(cfg.Endpoint); err != nil {
+		return fmt.Errorf("debug endpoint: %w", err)
+	}
+	return nil
+}
+
+// TailSamplingConfig configures tail-based trace sampling.
+type TailSamplingConfig struct {
+	// Enabled turns on tail-based trace sampling.
+	Enabled bool `mapstructure:"enabled"`
+
+	// DecisionWait is how long the spans of a trace are buffered, from its first span, before the sampling
+	// decision is made. Default is 30s.
+	DecisionWait time.Duration `mapstructure:"decision_wait"`
+
+	// MaxTraces bounds the number of traces buffered. When the limit is exceeded, the traces buffered longest are
+	// decided before their decision wait is over. Default is 50000.
+	MaxTraces int `mapstructure:"max_traces"`
+
+	// MaxSpans bounds the number of spans buffered, like MaxTraces. Default is 500000.
+	MaxSpans int `mapstructure:"max_spans"`
+
+	// DecisionCacheSize is the number of past decisions remembered, so that spans arriving after the decision for
+	// their trace was made are kept or dropped with the rest of the trace. Default is 100000.
+	DecisionCacheSize int `mapstructure:"decision_cache_size"`
+}
+
+func (cfg *TailSamplingConfig) validate() error {
+	if cfg.DecisionWait < 0 {
+		return errors.New("tail_sampling decision_wait must not be negative")
+	}
+	if cfg.MaxTraces < 0 || cfg.MaxSpans < 0 {
+		return errors.New("tail_sampling max_traces and max_spans must not be negative")
+	}
+	if cfg.DecisionCacheSize < 0 {
+		return errors.New("tail_sampling decision_cache_size must not be negative")
+	}
+	return nil
+}
+
+// ScheduleConfig configures a recurring time window during which the goal of the default sampler is overridden.
+type ScheduleConfig struct {
+	// Name identifies the schedule in logs and error messages.
+	Name string `mapstructure:"name"`
+
+	// Cron is a five field cron expression, such as "0 9 * * mon-fri", for the minutes the window opens at.
+	Cron string `mapstructure:"cron"`
+
+	// Duration is how long the window stays open. It must be a whole number of minutes.
+	Duration time.Duration `mapstructure:"duration"`
+
+	// TimeZone is the IANA name of the time zone Cron is evaluated in, such as "America/New_York". Default is UTC.
+	TimeZone string `mapstructure:"time_zone"`
+
+	// GoalSampleRate replaces the goal of samplers with a sample rate goal while the window is open.
+	GoalSampleRate int `mapstructure:"goal_sample_rate"`
+
+	// GoalThroughputPerSecond replaces the goal of samplers with a throughput goal while the window is open. It may
+	// be fractional for WindowedThroughputSampler.
+	GoalThroughputPerSecond float64 `mapstructure:"goal_throughput_per_second"`
+}
+
+func (cfg *ScheduleConfig) validate(sampler SamplerConfig) error {
+	if _, err := parseCron(cfg.Cron); err != nil {
+		return err
+	}
+	if cfg.Duration <= 0 || cfg.Duration%time.Minute != 0 {
+		return errors.New("duration must be a positive whole number of minutes")
+	}
+	if _, err := time.LoadLocation(cfg.TimeZone); err != nil {
+		return fmt.Errorf("time_zone: %w", err)
+	}
+
+	if sampler.Sampler != WindowedThroughputSampler && cfg.GoalThroughputPerSecond != math.Trunc(cfg.GoalThroughputPerSecond) {
+		return fmt.Errorf("goal_throughput_per_second must be a whole number for %s", sampler.Sampler)
+	}
+	scheduled := sampler.withGoal(cfg.GoalSampleRate, cfg.GoalThroughputPerSecond)
+	if scheduled.goals() == (Goals{}) {
+		if sampler.withGoal(1, 0).goals().GoalSampleRate > 0 {
+			return fmt.Errorf("goal_sample_rate must be set and greater than 0 for %s", sampler.Sampler)
+		}
+		return fmt.Errorf("goal_throughput_per_second must be set and greater than 0 for %s", sampler.Sampler)
+	}
+	return scheduled.validate()
+}
+
+// BudgetPeriod is the period an event budget applies to.
+type BudgetPeriod string
+
+const (
+	// BudgetPeriodDaily budgets are reset at midnight UTC.
+	BudgetPeriodDaily BudgetPeriod = "daily"
+	// BudgetPeriodMonthly budgets are reset at midnight UTC on the first day of the month.
+	BudgetPeriodMonthly BudgetPeriod = "monthly"
+)
+
+// BudgetConfig configures budget-driven goals. The goal of the default sampler is raised, for sample rate goals, or
+// lowered, for throughput goals, when events are recorded faster than the rest of the budget allows for the rest of
+// the period, and relaxed back towards the configured goal when there is headroom.
+type BudgetConfig struct {
+	// UsageSourceID is the ID of an extension implementing UsageSource, such as the honeycomb extension. Goals are
+	// not adjusted when it is not set.
+	UsageSourceID *component.ID `mapstructure:"usage_source"`
+
+	// Events is the number of events the budget allows per period. Required when usage_source is set.
+	Events int64 `mapstructure:"events"`
+
+	// Period is the period the budget applies to, daily or monthly. Default is monthly.
+	Period BudgetPeriod `mapstructure:"period"`
+
+	// AdjustmentInterval is how often the goal is adjusted. Default is 1m.
+	AdjustmentInterval time.Duration `mapstructure:"adjustment_interval"`
+}
+
+func (cfg *BudgetConfig) validate() error {
+	if cfg.UsageSourceID == nil {
+		return nil
+	}
+	if cfg.Events <= 0 {
+		return errors.New("budget events must be set and greater than 0")
+	}
+	switch cfg.Period {
+	case "", BudgetPeriodDaily, BudgetPeriodMonthly:
+	default:
+		return fmt.Errorf("budget period must be one of the following: %s, %s", BudgetPeriodDaily, BudgetPeriodMonthly)
+	}
+	if cfg.AdjustmentInterval < 0 {
+		return errors.New("budget adjustment_interval must not be negative")
+	}
+	return nil
+}
+
+// SummariesConfig configures the summary log records of sampled-away records.
+type SummariesConfig struct {
+	// Enabled turns on summary log records. Summaries are only emitted by the logs processor.
+	Enabled bool `mapstructure:"enabled"`
+
+	// Interval is how often summary log records are emitted. Default is 1m.
+	Interval time.Duration `mapstructure:"interval"`
+}
+
+func (cfg *SummariesConfig) validate() error {
+	if cfg.Interval < 0 {
+		return errors.New("summaries interval must not be negative")
+	}
+	return nil
+}
+
+// LogTemplatesConfig configures the clustering of log bodies into templates for the body_template field.
+type LogTemplatesConfig struct {
+	// SimilarityThreshold is the fraction of tokens, between 0 and 1, that a masked log body must share with a
+	// template to be clustered into it. Default is 0.5.
+	SimilarityThreshold float64 `mapstructure:"similarity_threshold"`
+
+	// MaxTemplates bounds the number of templates kept. Bodies that match no template once the limit is reached
+	// get the ID of their masked body without it being kept as a template. Default is 1000.
+	MaxTemplates int `mapstructure:"max_templates"`
+}
+
+func (cfg *LogTemplatesConfig) validate() error {
+	if cfg.SimilarityThreshold < 0 || cfg.SimilarityThreshold > 1 {
+		return errors.New("log_templates similarity_threshold must be between 0 and 1")
+	}
+	if cfg.MaxTemplates < 0 {
+		return errors.New("log_templates max_templates must not be negative")
+	}
+	return nil
+}
+
+// DeterministicConfig configures deterministic sampling. With the same field, every collector makes the same
+// decision for records that share a value, for example all logs and spans of a trace.
+type DeterministicConfig struct {
+	// Enabled turns on deterministic sampling decisions.
+	Enabled bool `mapstructure:"enabled"`
+
+	// Field is the field whose value is hashed, looked up like key_fields. Records without a value for the field
+	// are sampled at random. Default is trace_id.
+	Field string `mapstructure:"field"`
+}
+
+// SamplerConfig configures a dynamic sampler and the key it samples on.
+type SamplerConfig struct {
 	Sampler   string   `mapstructure:"sampler"`
 	KeyFields []string `mapstructure:"key_fields"`
 
+	// KeyExpressions is a list of OTTL value expressions whose results are added to the sampler key. Expressions
+	// are evaluated in the log context for logs and in the span context for traces.
+	KeyExpressions []string `mapstructure:"key_expressions"`
+
 	// EMADynamicSampler specific configuration
 	GoalSampleRate int `mapstructure:"goal_sample_rate"`
 
 	// EMAThroughputSampler specific configuration
 	GoalThroughputPerSecond int `mapstructure:"goal_throughput_per_second"`
+
+	// The following options tune both EMADynamicSampler and EMAThroughputSampler. A zero value uses the
+	// dynsampler-go default.
+
+	// AdjustmentInterval is how often the moving average and sample rates are recomputed. Default is 15s.
+	AdjustmentInterval time.Duration `mapstructure:"adjustment_interval"`
+
+	// Weight is the weight given to the most recent interval when updating the moving average. Must be between
+	// 0 and 1, where a larger value makes the sampler react faster to changes in traffic. Default is 0.5.
+	Weight float64 `mapstructure:"weight"`
+
+	// AgeOutValue is the moving average below which a key is removed from the sampler. Default is the value of
+	// Weight.
+	AgeOutValue float64 `mapstructure:"age_out_value"`
+
+	// BurstMultiple is the multiple of the moving average total that, when reached within an interval, triggers
+	// an early recomputation of the sample rates. Default is 2.
+	BurstMultiple float64 `mapstructure:"burst_multiple"`
+
+	// BurstDetectionDelay is the number of intervals to wait after startup before burst detection is enabled.
+	// Default is 3.
+	BurstDetectionDelay uint `mapstructure:"burst_detection_delay"`
+
+	// MaxKeys limits the number of distinct keys tracked per interval. Keys seen after the limit is reached are
+	// still sampled but do not count towards the moving average. Default is 0 (unlimited).
+	MaxKeys int `mapstructure:"max_keys"`
+
+	// AvgSampleRate is the configuration used by the AvgSampleRateSampler.
+	AvgSampleRate AvgSampleRateConfig `mapstructure:"avg_sample_rate"`
+
+	// AvgSampleWithMin is the configuration used by the AvgSampleWithMinSampler.
+	AvgSampleWithMin AvgSampleWithMinConfig `mapstructure:"avg_sample_with_min"`
+
+	// TotalThroughput is the configuration used by the TotalThroughputSampler.
+	TotalThroughput TotalThroughputConfig `mapstructure:"total_throughput"`
+
+	// PerKeyThroughput is the configuration used by the PerKeyThroughputSampler.
+	PerKeyThroughput PerKeyThroughputConfig `mapstructure:"per_key_throughput"`
+
+	// OnlyOnce is the configuration used by the OnlyOnceSampler.
+	OnlyOnce OnlyOnceConfig `mapstructure:"only_once"`
+
+	// Static is the configuration used by the StaticSampler.
+	Static StaticConfig `mapstructure:"static"`
+
+	// WindowedThroughput is the configuration used by the WindowedThroughputSampler.
+	WindowedThroughput WindowedThroughputConfig `mapstructure:"windowed_throughput"`
+
+	// PartitionBy is a field, looked up like key_fields, whose value selects a separate sampler instance. Each
+	// partition is sampled against its own goal, so one busy partition cannot use up the goal of the others.
+	PartitionBy string `mapstructure:"partition_by"`
+
+	// Partitions overrides the sampler goal for individual values of PartitionBy.
+	Partitions map[string]PartitionConfig `mapstructure:"partitions"`
+
+	// PartitionIdleTimeout is how long a partition can go without records before its sampler is stopped and
+	// removed. Default is 5m.
+	PartitionIdleTimeout time.Duration `mapstructure:"partition_idle_timeout"`
+
+	// MaxPartitions caps the number of running partitions. Records with values first seen after the cap is
+	// reached share the __overflow__ partition. Values in Partitions always get their own partition. Default is
+	// 1000.
+	MaxPartitions int `mapstructure:"max_partitions"`
+
+	// KeyLimit caps the number of distinct keys per KeyLimitInterval, across all partitions. Records with keys
+	// first seen after the cap is reached share the __overflow__ key, which is sampled like any other key. Default
+	// is 0 (unlimited).
+	KeyLimit int `mapstructure:"key_limit"`
+
+	// KeyLimitInterval is how long distinct keys are counted towards KeyLimit before counting starts over. Default
+	// is 1m.
+	KeyLimitInterval time.Duration `mapstructure:"key_limit_interval"`
+
+	// Shards is the number of samplers that keys are spread over by a hash of the key, so that records with
+	// different keys are sampled in parallel. Goals and limits on the total traffic of the sampler are divided
+	// evenly between the shards. Default is 1.
+	Shards int `mapstructure:"shards"`
+}
+
+// PartitionConfig overrides the goal of the sampler used for a single partition. Only the goal used by the
+// configured sampler is applied.
+type PartitionConfig struct {
+	// GoalSampleRate overrides the goal sample rate of EMADynamicSampler, AvgSampleRateSampler and
+	// AvgSampleWithMinSampler.
+	GoalSampleRate int `mapstructure:"goal_sample_rate"`
+
+	// GoalThroughputPerSecond overrides the goal throughput of EMAThroughputSampler, TotalThroughputSampler,
+	// PerKeyThroughputSampler and WindowedThroughputSampler.
+	GoalThroughputPerSecond int `mapstructure:"goal_throughput_per_second"`
+}
+
+// AvgSampleRateConfig configures a sampler that aims for an average sample rate across all keys, giving rare
+// keys a lower sample rate than frequent ones.
+type AvgSampleRateConfig struct {
+	// GoalSampleRate is the average sample rate to aim for across all keys. Required.
+	GoalSampleRate int `mapstructure:"goal_sample_rate"`
+
+	// ClearFrequency is how often the counters reset. Default is 30s.
+	ClearFrequency time.Duration `mapstructure:"clear_frequency"`
+
+	// MaxKeys limits the number of distinct keys tracked per interval. Default is 0 (unlimited).
+	MaxKeys int `mapstructure:"max_keys"`
+}
+
+func (cfg *AvgSampleRateConfig) validate() error {
+	if cfg.GoalSampleRate <= 0 {
+		return errors.New("avg_sample_rate goal_sample_rate must be set and greater than 0")
+	}
+	if cfg.ClearFrequency < 0 {
+		return errors.New("avg_sample_rate clear_frequency must not be negative")
+	}
+	if cfg.MaxKeys < 0 {
+		return errors.New("avg_sample_rate max_keys must not be negative")
+	}
+	return nil
+}
+
+// AvgSampleWithMinConfig configures an AvgSampleRate sampler that stops sampling when traffic drops below a
+// minimum number of events per second.
+type AvgSampleWithMinConfig struct {
+	// GoalSampleRate is the average sample rate to aim for across all keys. Required.
+	GoalSampleRate int `mapstructure:"goal_sample_rate"`
+
+	// MinEventsPerSecond is the total throughput below which sampling ceases. Default is 50.
+	MinEventsPerSecond int `mapstructure:"min_events_per_second"`
+
+	// ClearFrequency is how often the counters reset. Default is 30s.
+	ClearFrequency time.Duration `mapstructure:"clear_frequency"`
+
+	// MaxKeys limits the number of distinct keys tracked per interval. Default is 0 (unlimited).
+	MaxKeys int `mapstructure:"max_keys"`
+}
+
+func (cfg *AvgSampleWithMinConfig) validate() error {
+	if cfg.GoalSampleRate <= 0 {
+		return errors.New("avg_sample_with_min goal_sample_rate must be set and greater than 0")
+	}
+	if cfg.MinEventsPerSecond < 0 {
+		return errors.New("avg_sample_with_min min_events_per_second must not be negative")
+	}
+	if cfg.ClearFrequency < 0 {
+		return errors.New("avg_sample_with_min clear_frequency must not be negative")
+	}
+	if cfg.MaxKeys < 0 {
+		return errors.New("avg_sample_with_min max_keys must not be negative")
+	}
+	return nil
+}
+
+// TotalThroughputConfig configures a sampler that aims for a total number of events per second, shared
+// evenly between all keys.
+type TotalThroughputConfig struct {
+	// GoalThroughputPerSecond is the target number of events to send per second across all keys. Required.
+	GoalThroughputPerSecond int `mapstructure:"goal_throughput_per_second"`
+
+	// ClearFrequency is how often the counters reset. Default is 30s.
+	ClearFrequency time.Duration `mapstructure:"clear_frequency"`
+
+	// MaxKeys limits the number of distinct keys tracked per interval. Default is 0 (unlimited).
+	MaxKeys int `mapstructure:"max_keys"`
+}
+
+func (cfg *TotalThroughputConfig) validate() error {
+	if cfg.GoalThroughputPerSecond <= 0 {
+		return errors.New("total_throughput goal_throughput_per_second must be set and greater than 0")
+	}
+	if cfg.ClearFrequency < 0 {
+		return errors.New("total_throughput clear_frequency must not be negative")
+	}
+	if cfg.MaxKeys < 0 {
+		return errors.New("total_throughput max_keys must not be negative")
+	}
+	return nil
+}
+
+// PerKeyThroughputConfig configures a sampler that aims for a number of events per second for each key.
+type PerKeyThroughputConfig struct {
+	// PerKeyThroughputPerSecond is the target number of events to send per second for each key. Required.
+	PerKeyThroughputPerSecond int `mapstructure:"per_key_throughput_per_second"`
+
+	// ClearFrequency is how often the counters reset. Default is 30s.
+	ClearFrequency time.Duration `mapstructure:"clear_frequency"`
+
+	// MaxKeys limits the number of distinct keys tracked per interval. Default is 0 (unlimited).
+	MaxKeys int `mapstructure:"max_keys"`
+}
+
+func (cfg *PerKeyThroughputConfig) validate() error {
+	if cfg.PerKeyThroughputPerSecond <= 0 {
+		return errors.New("per_key_throughput per_key_throughput_per_second must be set and greater than 0")
+	}
+	if cfg.ClearFrequency < 0 {
+		return errors.New("per_key_throughput clear_frequency must not be negative")
+	}
+	if cfg.MaxKeys < 0 {
+		return errors.New("per_key_throughput max_keys must not be negative")
+	}
+	return nil
+}
+
+// OnlyOnceConfig configures a sampler that keeps the first event of each key per interval and drops the rest.
+type OnlyOnceConfig struct {
+	// ClearFrequency is how often the set of seen keys resets. Default is 30s.
+	ClearFrequency time.Duration `mapstructure:"clear_frequency"`
+}
+
+func (cfg *OnlyOnceConfig) validate() error {
+	if cfg.ClearFrequency < 0 {
+		return errors.New("only_once clear_frequency must not be negative")
+	}
+	return nil
+}
+
+// StaticConfig configures a sampler that uses fixed sample rates per key.
+type StaticConfig struct {
+	// Rates maps keys to the sample rate to use for them.
+	Rates map[string]int `mapstructure:"rates"`
+
+	// Default is the sample rate used for keys not found in Rates. Default is 1.
+	Default int `mapstructure:"default"`
+}
+
+func (cfg *StaticConfig) validate() error {
+	if cfg.Default < 0 {
+		return errors.New("static default must not be negative")
+	}
+	for key, rate := range cfg.Rates {
+		if rate <= 0 {
+			return fmt.Errorf("static rate for key %q must be greater than 0", key)
+		}
+	}
+	return nil
+}
+
+// WindowedThroughputConfig configures a throughput sampler that recomputes sample rates over a rolling window.
+type WindowedThroughputConfig struct {
+	// GoalThroughputPerSecond is the target number of events to send per second across all keys. Required.
+	GoalThroughputPerSecond float64 `mapstructure:"goal_throughput_per_second"`
+
+	// UpdateFrequency is how often the sample rates are recomputed. Default is 1s.
+	UpdateFrequency time.Duration `mapstructure:"update_frequency"`
+
+	// LookbackFrequency is how far back the sampler looks when computing sample rates. It is rounded down to a
+	// multiple of UpdateFrequency. Default is 30 times UpdateFrequency.
+	LookbackFrequency time.Duration `mapstructure:"lookback_frequency"`
+
+	// MaxKeys limits the number of distinct keys tracked per window. Default is 0 (unlimited).
+	MaxKeys int `mapstructure:"max_keys"`
+}
+
+func (cfg *WindowedThroughputConfig) validate() error {
+	if cfg.GoalThroughputPerSecond <= 0 {
+		return errors.New("windowed_throughput goal_throughput_per_second must be set and greater than 0")
+	}
+	if cfg.UpdateFrequency < 0 {
+		return errors.New("windowed_throughput update_frequency must not be negative")
+	}
+	if cfg.LookbackFrequency < 0 {
+		return errors.New("windowed_throughput lookback_frequency must not be negative")
+	}
+	if cfg.LookbackFrequency > 0 && cfg.LookbackFrequency < cfg.UpdateFrequency {
+		return errors.New("windowed_throughput lookback_frequency must not be less than update_frequency")
+	}
+	if cfg.MaxKeys < 0 {
+		return errors.New("windowed_throughput max_keys must not be negative")
+	}
+	return nil
+}
+
+// ThroughputUnit is what the samplers count for each record.
+type ThroughputUnit string
+
+const (
+	// ThroughputUnitRecords counts each record as one event.
+	ThroughputUnitRecords ThroughputUnit = "records"
+	// ThroughputUnitBytes counts each record as its size in bytes. For traces, the size of a trace is the size of
+	// its spans in the batch.
+	ThroughputUnitBytes ThroughputUnit = "bytes"
+)
+
+// RuleAction is what a rule does with the records that match it.
+type RuleAction string
+
+const (
+	// RuleActionKeep keeps every matching record.
+	RuleActionKeep RuleAction = "keep"
+	// RuleActionDrop drops every matching record.
+	RuleActionDrop RuleAction = "drop"
+	// RuleActionSampleRate samples matching records at a fixed sample rate.
+	RuleActionSampleRate RuleAction = "sample_rate"
+	// RuleActionSampler hands matching records off to a named sampler.
+	RuleActionSampler RuleAction = "sampler"
+)
+
+// SampleRateClampConfig limits the sample rates computed for the records it matches.
+type SampleRateClampConfig struct {
+	// Key matches records whose sampler key is exactly Key.
+	Key string `mapstructure:"key"`
+
+	// Conditions is a list of OTTL conditions that must all be true for a record to match. When Key is set too,
+	// records must match both.
+	Conditions []string `mapstructure:"conditions"`
+
+	// MinSampleRate is the lowest sample rate applied to matching records, so they are sampled at least this hard.
+	// Default is min_sample_rate.
+	MinSampleRate int `mapstructure:"min_sample_rate"`
+
+	// MaxSampleRate is the highest sample rate applied to matching records, so they are never sampled harder.
+	// Default is max_sample_rate.
+	MaxSampleRate int `mapstructure:"max_sample_rate"`
+}
+
+func (cfg *SampleRateClampConfig) validate() error {
+	if cfg.Key == "" && len(cfg.Conditions) == 0 {
+		return errors.New("key or conditions must be set")
+	}
+	if cfg.MinSampleRate == 0 && cfg.MaxSampleRate == 0 {
+		return errors.New("min_sample_rate or max_sample_rate must be set")
+	}
+	return validateSampleRateLimits(cfg.MinSampleRate, cfg.MaxSampleRate)
+}
+
+// validateSampleRateLimits checks a floor and ceiling of sample rates, where zero means no limit.
+func validateSampleRateLimits(minSampleRate, maxSampleRate int) error {
+	if minSampleRate < 0 || maxSampleRate < 0 {
+		return errors.New("min_sample_rate and max_sample_rate must not be negative")
+	}
+	if minSampleRate > 0 && maxSampleRate > 0 && minSampleRate > maxSampleRate {
+		return errors.New("min_sample_rate must not be greater than max_sample_rate")
+	}
+	return nil
+}
+
+// RuleConfig configures a sampling rule.
+type RuleConfig struct {
+	// Name identifies the rule in logs and error messages.
+	Name string `mapstructure:"name"`
+
+	// Conditions is a list of OTTL conditions that must all be true for a record to match the rule. A rule
+	// without conditions matches every record.
+	Conditions []string `mapstructure:"conditions"`
+
+	// Action is what the rule does with matching records. One of keep, drop, sample_rate or sampler.
+	Action RuleAction `mapstructure:"action"`
+
+	// SampleRate is the fixed sample rate used by the sample_rate action.
+	SampleRate int `mapstructure:"sample_rate"`
+
+	// Sampler is the name of the sampler, from the samplers section, used by the sampler action.
+	Sampler string `mapstructure:"sampler"`
 }
 
 var _ component.Config = (*Config)(nil)
 
 func (cfg *Config) Validate() error {
+	if err := cfg.SamplerConfig.validate(); err != nil {
+		return err
+	}
+
+	for name, samplerCfg := range cfg.Samplers {
+		if name == defaultSamplerName {
+			return fmt.Errorf("samplers::%s: the name %q is reserved for the default sampler", name, defaultSamplerName)
+		}
+		if err := samplerCfg.validate(); err != nil {
+			return fmt.Errorf("samplers::%s: %w", name, err)
+		}
+	}
+
+	for i, rule := range cfg.Rules {
+		if err := rule.validate(cfg.Samplers); err != nil {
+			return fmt.Errorf("rules[%d]: %w", i, err)
+		}
+	}
+
+	if err := validateSampleRateLimits(cfg.MinSampleRate, cfg.MaxSampleRate); err != nil {
+		return err
+	}
+	for i, clamp := range cfg.SampleRateClamps {
+		if err := clamp.validate(); err != nil {
+			return fmt.Errorf("sample_rate_clamps[%d]: %w", i, err)
+		}
+	}
+
+	if err := cfg.TailSampling.validate(); err != nil {
+		return err
+	}
+
+	if err := cfg.Budget.validate(); err != nil {
+		return err
+	}
+	if cfg.Budget.UsageSourceID != nil {
+		if cfg.GoalSourceID != nil {
+			return errors.New("budget and goal_source cannot both be set")
+		}
+		switch cfg.Sampler {
+		case OnlyOnceSampler, StaticSampler:
+			return fmt.Errorf("budget cannot be used with %s, which has no goal", cfg.Sampler)
+		}
+	}
+
+	if len(cfg.Schedules) > 0 {
+		switch cfg.Sampler {
+		case OnlyOnceSampler, StaticSampler:
+			return fmt.Errorf("schedules cannot be used with %s, which has no goal", cfg.Sampler)
+		}
+		if cfg.Budget.UsageSourceID != nil {
+			return errors.New("schedules and budget cannot both be set")
+		}
+		if cfg.GoalSourceID != nil {
+			return errors.New("schedules and goal_source cannot both be set")
+		}
+	}
+	for i, schedule := range cfg.Schedules {
+		if err := schedule.validate(cfg.SamplerConfig); err != nil {
+			return fmt.Errorf("schedules[%d]: %w", i, err)
+		}
+	}
+
+	if err := cfg.Summaries.validate(); err != nil {
+		return err
+	}
+
+	if err := cfg.LogTemplates.validate(); err != nil {
+		return err
+	}
+
+	if err := cfg.Debug.validate(); err != nil {
+		return err
+	}
+
+	switch cfg.ThroughputUnit {
+	case "", ThroughputUnitRecords:
+	case ThroughputUnitBytes:
+		if !cfg.hasThroughputGoal() {
+			return fmt.Errorf("throughput_unit %s cannot be used with %s, which has no throughput goal", ThroughputUnitBytes, cfg.Sampler)
+		}
+		for name, samplerCfg := range cfg.Samplers {
+			if !samplerCfg.hasThroughputGoal() {
+				return fmt.Errorf("samplers::%s: throughput_unit %s cannot be used with %s, which has no throughput goal", name, ThroughputUnitBytes, samplerCfg.Sampler)
+			}
+		}
+	default:
+		return fmt.Errorf("throughput_unit must be one of the following: %s, %s", ThroughputUnitRecords, ThroughputUnitBytes)
+	}
+
+	if cfg.StateSnapshotInterval < 0 {
+		return errors.New("state_snapshot_interval must not be negative")
+	}
+
+	return nil
+}
+
+func (rule *RuleConfig) validate(samplers map[string]SamplerConfig) error {
+	switch rule.Action {
+	case RuleActionKeep, RuleActionDrop:
+	case RuleActionSampleRate:
+		if rule.SampleRate <= 0 {
+			return errors.New("sample_rate must be set and greater than 0 for the sample_rate action")
+		}
+	case RuleActionSampler:
+		if rule.Sampler == "" {
+			return errors.New("sampler must be set for the sampler action")
+		}
+		if _, ok := samplers[rule.Sampler]; !ok {
+			return fmt.Errorf("sampler %q is not defined in samplers", rule.Sampler)
+		}
+	default:
+		return fmt.Errorf("action must be one of the following: %s, %s, %s, %s", RuleActionKeep, RuleActionDrop, RuleActionSampleRate, RuleActionSampler)
+	}
+	return nil
+}
+
+func (cfg *SamplerConfig) validate() error {
 	if cfg.Sampler == "" {
-		return fmt.Errorf("sampler must be set. Valid options: %s, %s", EMADynamicSampler, EMAThroughputSampler)
+		return fmt.Errorf("sampler must be set. Valid options: %s", strings.Join(validSamplers, ", "))
 	}
 
-	if cfg.Sampler != EMADynamicSampler && cfg.Sampler != EMAThroughputSampler {
-		return fmt.Errorf("sampler must be set to one of the following: %s, %s", EMADynamicSampler, EMAThroughputSampler)
+	if !slices.Contains(validSamplers, cfg.Sampler) {
+		return fmt.Errorf("sampler must be set to one of the following: %s", strings.Join(validSamplers, ", "))
 	}
 
-	if len(cfg.KeyFields) == 0 {
-		return fmt.Errorf("Must set at least one attribute to use as a key for dynamic sampling")
+	if len(cfg.KeyFields) == 0 && len(cfg.KeyExpressions) == 0 {
+		return fmt.Errorf("Must set at least one attribute or key expression to use as a key for dynamic sampling")
 	}
 
-	if cfg.Sampler == EMADynamicSampler && cfg.GoalSampleRate <= 0 {
-		return fmt.Errorf("EMADynamicSampler goal_sample_rate must be set and greater than 0")
+	if err := cfg.validatePartitions(); err != nil {
+		return err
 	}
 
-	if cfg.Sampler == EMAThroughputSampler && cfg.GoalThroughputPerSecond <= 0 {
-		return fmt.Errorf("EMAThroughputSampler goal_throughput_per_second must be set and greater than 0")
+	if cfg.KeyLimit < 0 {
+		return errors.New("key_limit must not be negative")
+	}
+	if cfg.KeyLimitInterval < 0 {
+		return errors.New("key_limit_interval must not be negative")
+	}
+	if cfg.Shards < 0 {
+		return errors.New("shards must not be negative")
 	}
 
+	switch cfg.Sampler {
+	case EMADynamicSampler:
+		if cfg.GoalSampleRate <= 0 {
+			return fmt.Errorf("EMADynamicSampler goal_sample_rate must be set and greater than 0")
+		}
+		return cfg.validateEMA()
+	case EMAThroughputSampler:
+		if cfg.GoalThroughputPerSecond <= 0 {
+			return fmt.Errorf("EMAThroughputSampler goal_throughput_per_second must be set and greater than 0")
+		}
+		return cfg.validateEMA()
+	case AvgSampleRateSampler:
+		return cfg.AvgSampleRate.validate()
+	case AvgSampleWithMinSampler:
+		return cfg.AvgSampleWithMin.validate()
+	case TotalThroughputSampler:
+		return cfg.TotalThroughput.validate()
+	case PerKeyThroughputSampler:
+		return cfg.PerKeyThroughput.validate()
+	case OnlyOnceSampler:
+		return cfg.OnlyOnce.validate()
+	case StaticSampler:
+		return cfg.Static.validate()
+	case WindowedThroughputSampler:
+		return cfg.WindowedThroughput.validate()
+	}
+
+	return nil
+}
+
+// validatePartitions checks the partition options.
+func (cfg *SamplerConfig) validatePartitions() error {
+	if cfg.PartitionIdleTimeout < 0 {
+		return errors.New("partition_idle_timeout must not be negative")
+	}
+	if cfg.MaxPartitions < 0 {
+		return errors.New("max_partitions must not be negative")
+	}
+	if len(cfg.Partitions) == 0 {
+		return nil
+	}
+	if cfg.PartitionBy == "" {
+		return errors.New("partition_by must be set to use partitions")
+	}
+	for value, partition := range cfg.Partitions {
+		if partition.GoalSampleRate < 0 || partition.GoalThroughputPerSecond < 0 {
+			return fmt.Errorf("partitions::%s goals must not be negative", value)
+		}
+		switch cfg.Sampler {
+		case EMADynamicSampler, AvgSampleRateSampler, AvgSampleWithMinSampler:
+			if partition.GoalSampleRate == 0 {
+				return fmt.Errorf("partitions::%s goal_sample_rate must be set for %s", value, cfg.Sampler)
+			}
+		case EMAThroughputSampler, TotalThroughputSampler, PerKeyThroughputSampler, WindowedThroughputSampler:
+			if partition.GoalThroughputPerSecond == 0 {
+				return fmt.Errorf("partitions::%s goal_throughput_per_second must be set for %s", value, cfg.Sampler)
+			}
+		default:
+			return fmt.Errorf("partitions::%s %s has no goal to override", value, cfg.Sampler)
+		}
+	}
+	return nil
+}
+
+// withGoal returns a copy of the config with the goal used by its sampler replaced by goalSampleRate or
+// goalThroughputPerSecond. Samplers with a whole number throughput goal get goalThroughputPerSecond rounded down,
+// to no less than 1.
+func (cfg SamplerConfig) withGoal(goalSampleRate int, goalThroughputPerSecond float64) SamplerConfig {
+	switch cfg.Sampler {
+	case EMADynamicSampler:
+		cfg.GoalSampleRate = goalSampleRate
+	case EMAThroughputSampler:
+		cfg.GoalThroughputPerSecond = wholeThroughput(goalThroughputPerSecond)
+	case AvgSampleRateSampler:
+		cfg.AvgSampleRate.GoalSampleRate = goalSampleRate
+	case AvgSampleWithMinSampler:
+		cfg.AvgSampleWithMin.GoalSampleRate = goalSampleRate
+	case TotalThroughputSampler:
+		cfg.TotalThroughput.GoalThroughputPerSecond = wholeThroughput(goalThroughputPerSecond)
+	case PerKeyThroughputSampler:
+		cfg.PerKeyThroughput.PerKeyThroughputPerSecond = wholeThroughput(goalThroughputPerSecond)
+	case WindowedThroughputSampler:
+		cfg.WindowedThroughput.GoalThroughputPerSecond = goalThroughputPerSecond
+	}
+	return cfg
+}
+
+// wholeThroughput rounds a positive throughput goal down to a whole number, to no less than 1. Goals that are not
+// positive are left for validation to reject.
+func wholeThroughput(goal float64) int {
+	if goal <= 0 {
+		return int(goal)
+	}
+	return int(min(max(goal, 1), math.MaxInt32))
+}
+
+// defaultMinEventsPerSecond is the dynsampler-go default of min_events_per_second, which is divided between shards
+// like a configured value.
+const defaultMinEventsPerSecond = 50
+
+// shardConfig returns the config of each of the given number of shards of a sampler. Goals and limits on the total
+// traffic of the sampler are divided between the shards, rounding up, while sample rates and per key goals are
+// kept.
+func (cfg SamplerConfig) shardConfig(shards int) SamplerConfig {
+	cfg.Shards = 1
+	cfg.GoalThroughputPerSecond = divideShards(cfg.GoalThroughputPerSecond, shards)
+	cfg.MaxKeys = divideShards(cfg.MaxKeys, shards)
+	cfg.AvgSampleRate.MaxKeys = divideShards(cfg.AvgSampleRate.MaxKeys, shards)
+	if cfg.AvgSampleWithMin.MinEventsPerSecond == 0 {
+		cfg.AvgSampleWithMin.MinEventsPerSecond = defaultMinEventsPerSecond
+	}
+	cfg.AvgSampleWithMin.MinEventsPerSecond = divideShards(cfg.AvgSampleWithMin.MinEventsPerSecond, shards)
+	cfg.AvgSampleWithMin.MaxKeys = divideShards(cfg.AvgSampleWithMin.MaxKeys, shards)
+	cfg.TotalThroughput.GoalThroughputPerSecond = divideShards(cfg.TotalThroughput.GoalThroughputPerSecond, shards)
+	cfg.TotalThroughput.MaxKeys = divideShards(cfg.TotalThroughput.MaxKeys, shards)
+	cfg.PerKeyThroughput.MaxKeys = divideShards(cfg.PerKeyThroughput.MaxKeys, shards)
+	cfg.WindowedThroughput.GoalThroughputPerSecond /= float64(shards)
+	cfg.WindowedThroughput.MaxKeys = divideShards(cfg.WindowedThroughput.MaxKeys, shards)
+	return cfg
+}
+
+// divideShards divides value between the given number of shards, rounding up so that a positive value stays
+// positive.
+func divideShards(value, shards int) int {
+	if value <= 0 {
+		return value
+	}
+	return (value + shards - 1) / shards
+}
+
+// goals returns the goal used by the sampler of the config.
+func (cfg SamplerConfig) goals() Goals {
+	switch cfg.Sampler {
+	case EMADynamicSampler:
+		return Goals{GoalSampleRate: cfg.GoalSampleRate}
+	case EMAThroughputSampler:
+		return Goals{GoalThroughputPerSecond: float64(cfg.GoalThroughputPerSecond)}
+	case AvgSampleRateSampler:
+		return Goals{GoalSampleRate: cfg.AvgSampleRate.GoalSampleRate}
+	case AvgSampleWithMinSampler:
+		return Goals{GoalSampleRate: cfg.AvgSampleWithMin.GoalSampleRate}
+	case TotalThroughputSampler:
+		return Goals{GoalThroughputPerSecond: float64(cfg.TotalThroughput.GoalThroughputPerSecond)}
+	case PerKeyThroughputSampler:
+		return Goals{GoalThroughputPerSecond: float64(cfg.PerKeyThroughput.PerKeyThroughputPerSecond)}
+	case WindowedThroughputSampler:
+		return Goals{GoalThroughputPerSecond: cfg.WindowedThroughput.GoalThroughputPerSecond}
+	}
+	return Goals{}
+}
+
+// hasThroughputGoal reports whether the sampler of the config aims for a throughput rather than a sample rate.
+func (cfg SamplerConfig) hasThroughputGoal() bool {
+	switch cfg.Sampler {
+	case EMAThroughputSampler, TotalThroughputSampler, PerKeyThroughputSampler, WindowedThroughputSampler:
+		return true
+	}
+	return false
+}
+
+// validateEMA checks the tuning options shared by the EMA samplers.
+func (cfg *SamplerConfig) validateEMA() error {
+	if cfg.AdjustmentInterval < 0 {
+		return errors.New("adjustment_interval must not be negative")
+	}
+	if cfg.Weight < 0 || cfg.Weight > 1 {
+		return errors.New("weight must be between 0 and 1")
+	}
+	if cfg.AgeOutValue < 0 {
+		return errors.New("age_out_value must not be negative")
+	}
+	if cfg.BurstMultiple < 0 {
+		return errors.New("burst_multiple must not be negative")
+	}
+	if cfg.MaxKeys < 0 {
+		return errors.New("max_keys must not be negative")
+	}
 	return nil
 }
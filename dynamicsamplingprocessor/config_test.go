@@ -3,15 +3,22 @@ package dynamicsamplingprocessor
 import (
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go.opentelemetry.io/collector/component"
 	"go.opentelemetry.io/collector/confmap/confmaptest"
+	"go.opentelemetry.io/collector/confmap/xconfmap"
+
+	"github.com/honeycombio/opentelemetry-collector-configs/dynamicsamplingprocessor/internal/metadata"
 )
 
 func TestLoadConfig(t *testing.T) {
 	t.Parallel()
+	storageID := component.MustNewID("file_storage")
+	goalSourceID := component.MustNewID("remote_goals")
+	usageSourceID := component.MustNewID("honeycomb")
 	tests := []struct {
 		name     string
 		id       string
@@ -21,9 +28,444 @@ func TestLoadConfig(t *testing.T) {
 			name: "EMADynamicSampler correct config",
 			id:   "EMADynamicSampler",
 			expected: &Config{
-				Sampler:        EMADynamicSampler,
-				KeyFields:      []string{"key1", "key2"},
-				GoalSampleRate: 10,
+				SamplerConfig: SamplerConfig{
+					Sampler:        EMADynamicSampler,
+					KeyFields:      []string{"key1", "key2"},
+					GoalSampleRate: 10,
+				},
+			},
+		},
+		{
+			name: "EMAThroughputSampler correct config",
+			id:   "EMAThroughputSampler",
+			expected: &Config{
+				SamplerConfig: SamplerConfig{
+					Sampler:                 EMAThroughputSampler,
+					KeyFields:               []string{"key1", "key2"},
+					GoalSampleRate:          10,
+					GoalThroughputPerSecond: 100,
+				},
+			},
+		},
+		{
+			name: "EMA sampler with tuning options",
+			id:   "EMATuned",
+			expected: &Config{
+				SamplerConfig: SamplerConfig{
+					Sampler:                 EMAThroughputSampler,
+					KeyFields:               []string{"key1"},
+					GoalSampleRate:          10,
+					GoalThroughputPerSecond: 100,
+					AdjustmentInterval:      5 * time.Second,
+					Weight:                  0.2,
+					AgeOutValue:             0.1,
+					BurstMultiple:           3,
+					BurstDetectionDelay:     1,
+					MaxKeys:                 1000,
+				},
+			},
+		},
+		{
+			name: "key expressions without key fields",
+			id:   "KeyExpressions",
+			expected: &Config{
+				SamplerConfig: SamplerConfig{
+					Sampler:        EMADynamicSampler,
+					KeyFields:      []string{},
+					GoalSampleRate: 10,
+					KeyExpressions: []string{
+						`Substring(attributes["http.response.status_code"], 0, 1)`,
+						`Split(attributes["url.path"], "/")[1]`,
+					},
+				},
+			},
+		},
+		{
+			name: "AvgSampleRateSampler correct config",
+			id:   "AvgSampleRateSampler",
+			expected: &Config{
+				SamplerConfig: SamplerConfig{
+					Sampler:        AvgSampleRateSampler,
+					KeyFields:      []string{"key1"},
+					GoalSampleRate: 10,
+					AvgSampleRate: AvgSampleRateConfig{
+						GoalSampleRate: 20,
+						ClearFrequency: 10 * time.Second,
+						MaxKeys:        500,
+					},
+				},
+			},
+		},
+		{
+			name: "AvgSampleWithMinSampler correct config",
+			id:   "AvgSampleWithMinSampler",
+			expected: &Config{
+				SamplerConfig: SamplerConfig{
+					Sampler:        AvgSampleWithMinSampler,
+					KeyFields:      []string{"key1"},
+					GoalSampleRate: 10,
+					AvgSampleWithMin: AvgSampleWithMinConfig{
+						GoalSampleRate:     20,
+						MinEventsPerSecond: 5,
+					},
+				},
+			},
+		},
+		{
+			name: "TotalThroughputSampler correct config",
+			id:   "TotalThroughputSampler",
+			expected: &Config{
+				SamplerConfig: SamplerConfig{
+					Sampler:        TotalThroughputSampler,
+					KeyFields:      []string{"key1"},
+					GoalSampleRate: 10,
+					TotalThroughput: TotalThroughputConfig{
+						GoalThroughputPerSecond: 50,
+					},
+				},
+			},
+		},
+		{
+			name: "PerKeyThroughputSampler correct config",
+			id:   "PerKeyThroughputSampler",
+			expected: &Config{
+				SamplerConfig: SamplerConfig{
+					Sampler:        PerKeyThroughputSampler,
+					KeyFields:      []string{"key1"},
+					GoalSampleRate: 10,
+					PerKeyThroughput: PerKeyThroughputConfig{
+						PerKeyThroughputPerSecond: 5,
+					},
+				},
+			},
+		},
+		{
+			name: "OnlyOnceSampler correct config",
+			id:   "OnlyOnceSampler",
+			expected: &Config{
+				SamplerConfig: SamplerConfig{
+					Sampler:        OnlyOnceSampler,
+					KeyFields:      []string{"key1"},
+					GoalSampleRate: 10,
+					OnlyOnce: OnlyOnceConfig{
+						ClearFrequency: time.Minute,
+					},
+				},
+			},
+		},
+		{
+			name: "StaticSampler correct config",
+			id:   "StaticSampler",
+			expected: &Config{
+				SamplerConfig: SamplerConfig{
+					Sampler:        StaticSampler,
+					KeyFields:      []string{"key1"},
+					GoalSampleRate: 10,
+					Static: StaticConfig{
+						Default: 10,
+						Rates:   map[string]int{"audit": 1, "debug": 100},
+					},
+				},
+			},
+		},
+		{
+			name: "WindowedThroughputSampler correct config",
+			id:   "WindowedThroughputSampler",
+			expected: &Config{
+				SamplerConfig: SamplerConfig{
+					Sampler:        WindowedThroughputSampler,
+					KeyFields:      []string{"key1"},
+					GoalSampleRate: 10,
+					WindowedThroughput: WindowedThroughputConfig{
+						GoalThroughputPerSecond: 2.5,
+						UpdateFrequency:         2 * time.Second,
+						LookbackFrequency:       time.Minute,
+					},
+				},
+			},
+		},
+		{
+			name: "rules with a named sampler",
+			id:   "Rules",
+			expected: &Config{
+				SamplerConfig: SamplerConfig{
+					Sampler:        EMADynamicSampler,
+					KeyFields:      []string{"key1"},
+					GoalSampleRate: 10,
+				},
+				Rules: []RuleConfig{
+					{
+						Name:       "keep errors",
+						Conditions: []string{"severity_number >= SEVERITY_NUMBER_ERROR"},
+						Action:     RuleActionKeep,
+					},
+					{
+						Name:       "drop health checks",
+						Conditions: []string{`attributes["http.route"] == "/health"`},
+						Action:     RuleActionDrop,
+					},
+					{
+						Name:       "sample debug logs",
+						Conditions: []string{`severity_text == "DEBUG"`},
+						Action:     RuleActionSampleRate,
+						SampleRate: 100,
+					},
+					{
+						Name:       "audit logs",
+						Conditions: []string{`attributes["audit.action"] != nil`},
+						Action:     RuleActionSampler,
+						Sampler:    "audit",
+					},
+				},
+				Samplers: map[string]SamplerConfig{
+					"audit": {
+						Sampler:   StaticSampler,
+						KeyFields: []string{"audit.action"},
+						Static:    StaticConfig{Default: 5},
+					},
+				},
+			},
+		},
+		{
+			name: "partitioned sampler",
+			id:   "Partitioned",
+			expected: &Config{
+				SamplerConfig: SamplerConfig{
+					Sampler:                 EMAThroughputSampler,
+					KeyFields:               []string{"http.route"},
+					GoalSampleRate:          10,
+					GoalThroughputPerSecond: 100,
+					PartitionBy:             "service.name",
+					PartitionIdleTimeout:    10 * time.Minute,
+					Partitions: map[string]PartitionConfig{
+						"checkout": {GoalThroughputPerSecond: 500},
+					},
+				},
+			},
+		},
+		{
+			name: "deterministic sampling",
+			id:   "Deterministic",
+			expected: &Config{
+				SamplerConfig: SamplerConfig{
+					Sampler:        EMADynamicSampler,
+					KeyFields:      []string{"key1"},
+					GoalSampleRate: 10,
+				},
+				Deterministic: DeterministicConfig{
+					Enabled: true,
+					Field:   "request.id",
+				},
+			},
+		},
+		{
+			name: "upstream sample rate and probability sampling",
+			id:   "UpstreamSampleRate",
+			expected: &Config{
+				SamplerConfig: SamplerConfig{
+					Sampler:        EMADynamicSampler,
+					KeyFields:      []string{"key1"},
+					GoalSampleRate: 10,
+				},
+				SampleRateAttribute:        "sampleRate",
+				WeightByUpstreamSampleRate: true,
+				ProbabilitySampling:        true,
+			},
+		},
+		{
+			name: "sampler state persisted in a storage extension",
+			id:   "State",
+			expected: &Config{
+				SamplerConfig: SamplerConfig{
+					Sampler:        EMADynamicSampler,
+					KeyFields:      []string{"key1"},
+					GoalSampleRate: 10,
+				},
+				StorageID:             &storageID,
+				StateSnapshotInterval: 30 * time.Second,
+			},
+		},
+		{
+			name: "goals changed at runtime by an extension",
+			id:   "GoalSource",
+			expected: &Config{
+				SamplerConfig: SamplerConfig{
+					Sampler:        EMADynamicSampler,
+					KeyFields:      []string{"key1"},
+					GoalSampleRate: 10,
+				},
+				GoalSourceID: &goalSourceID,
+			},
+		},
+		{
+			name: "event budget",
+			id:   "Budget",
+			expected: &Config{
+				SamplerConfig: SamplerConfig{
+					Sampler:        EMADynamicSampler,
+					KeyFields:      []string{"key1"},
+					GoalSampleRate: 10,
+				},
+				Budget: BudgetConfig{
+					UsageSourceID:      &usageSourceID,
+					Events:             100000000,
+					Period:             BudgetPeriodDaily,
+					AdjustmentInterval: 5 * time.Minute,
+				},
+			},
+		},
+		{
+			name: "schedules",
+			id:   "Schedules",
+			expected: &Config{
+				SamplerConfig: SamplerConfig{
+					Sampler:        EMADynamicSampler,
+					KeyFields:      []string{"key1"},
+					GoalSampleRate: 10,
+				},
+				Schedules: []ScheduleConfig{
+					{
+						Name:           "business hours",
+						Cron:           "0 9 * * mon-fri",
+						Duration:       9 * time.Hour,
+						TimeZone:       "America/New_York",
+						GoalSampleRate: 50,
+					},
+					{
+						Name:           "nights",
+						Cron:           "0 22 * * *",
+						Duration:       8 * time.Hour,
+						GoalSampleRate: 2,
+					},
+				},
+			},
+		},
+		{
+			name: "debug page",
+			id:   "Debug",
+			expected: &Config{
+				SamplerConfig: SamplerConfig{
+					Sampler:        EMADynamicSampler,
+					KeyFields:      []string{"key1"},
+					GoalSampleRate: 10,
+				},
+				Debug: DebugConfig{Endpoint: "localhost:55680"},
+			},
+		},
+		{
+			name: "tail sampling",
+			id:   "TailSampling",
+			expected: &Config{
+				SamplerConfig: SamplerConfig{
+					Sampler:        EMADynamicSampler,
+					KeyFields:      []string{"span_name", "trace.has_error"},
+					GoalSampleRate: 10,
+				},
+				TailSampling: TailSamplingConfig{
+					Enabled:           true,
+					DecisionWait:      10 * time.Second,
+					MaxTraces:         1000,
+					MaxSpans:          20000,
+					DecisionCacheSize: 5000,
+				},
+			},
+		},
+		{
+			name: "dry run",
+			id:   "DryRun",
+			expected: &Config{
+				SamplerConfig: SamplerConfig{
+					Sampler:        EMADynamicSampler,
+					KeyFields:      []string{"key1"},
+					GoalSampleRate: 10,
+				},
+				DryRun: true,
+			},
+		},
+		{
+			name: "key limit",
+			id:   "KeyLimit",
+			expected: &Config{
+				SamplerConfig: SamplerConfig{
+					Sampler:          EMADynamicSampler,
+					KeyFields:        []string{"user.id"},
+					GoalSampleRate:   10,
+					KeyLimit:         500,
+					KeyLimitInterval: 5 * time.Minute,
+				},
+			},
+		},
+		{
+			name: "sample rate clamps",
+			id:   "SampleRateClamps",
+			expected: &Config{
+				SamplerConfig: SamplerConfig{
+					Sampler:        EMADynamicSampler,
+					KeyFields:      []string{"service.name"},
+					GoalSampleRate: 10,
+				},
+				MinSampleRate: 2,
+				MaxSampleRate: 1000,
+				SampleRateClamps: []SampleRateClampConfig{
+					{Key: "payments", MaxSampleRate: 5},
+					{Conditions: []string{`attributes["http.route"] == "/health"`}, MinSampleRate: 100},
+				},
+			},
+		},
+		{
+			name: "shards",
+			id:   "Shards",
+			expected: &Config{
+				SamplerConfig: SamplerConfig{
+					Sampler:                 EMAThroughputSampler,
+					KeyFields:               []string{"key1"},
+					GoalSampleRate:          10,
+					GoalThroughputPerSecond: 1000,
+					Shards:                  8,
+				},
+			},
+		},
+		{
+			name: "throughput in bytes",
+			id:   "ThroughputBytes",
+			expected: &Config{
+				SamplerConfig: SamplerConfig{
+					Sampler:                 EMAThroughputSampler,
+					KeyFields:               []string{"key1"},
+					GoalSampleRate:          10,
+					GoalThroughputPerSecond: 1_000_000,
+				},
+				ThroughputUnit: ThroughputUnitBytes,
+			},
+		},
+		{
+			name: "log templates",
+			id:   "LogTemplates",
+			expected: &Config{
+				SamplerConfig: SamplerConfig{
+					Sampler:        EMADynamicSampler,
+					KeyFields:      []string{"service.name", "body_template"},
+					GoalSampleRate: 10,
+				},
+				LogTemplates: LogTemplatesConfig{
+					SimilarityThreshold: 0.7,
+					MaxTemplates:        500,
+				},
+			},
+		},
+		{
+			name: "summaries",
+			id:   "Summaries",
+			expected: &Config{
+				SamplerConfig: SamplerConfig{
+					Sampler:        EMADynamicSampler,
+					KeyFields:      []string{"key1"},
+					GoalSampleRate: 10,
+				},
+				Summaries: SummariesConfig{
+					Enabled:  true,
+					Interval: 5 * time.Minute,
+				},
 			},
 		},
 	}
@@ -38,37 +480,405 @@ func TestLoadConfig(t *testing.T) {
 			factory := NewFactory()
 			cfg := factory.CreateDefaultConfig()
 
-			sub, err := processors.Sub(tt.id)
+			sub, err := processors.Sub(component.NewIDWithName(metadata.Type, tt.id).String())
 			require.NoError(t, err)
 			require.NoError(t, sub.Unmarshal(cfg))
 
-			assert.NoError(t, component.ValidateConfig(cfg))
+			assert.NoError(t, xconfmap.Validate(cfg))
 			assert.Equal(t, tt.expected, cfg)
 		})
 	}
 }
 
-// func TestLoadInvalidConfig(t *testing.T) {
-// 	for _, test := range []struct {
-// 		file     string
-// 		contains string
-// 	}{
-// 		{"invalid_negative.yaml", "sampling rate is negative"},
-// 		{"invalid_small.yaml", "sampling rate is too small"},
-// 		{"invalid_inf.yaml", "sampling rate is invalid: +Inf%"},
-// 		{"invalid_prec.yaml", "sampling precision is too great"},
-// 		{"invalid_zero.yaml", "invalid sampling precision"},
-// 	} {
-// 		t.Run(test.file, func(t *testing.T) {
-// 			factories, err := otelcoltest.NopFactories()
-// 			require.NoError(t, err)
+func TestValidateConfig(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name     string
+		modify   func(cfg *Config)
+		contains string
+	}{
+		{
+			name:     "missing sampler",
+			modify:   func(cfg *Config) { cfg.Sampler = "" },
+			contains: "sampler must be set",
+		},
+		{
+			name:     "unknown sampler",
+			modify:   func(cfg *Config) { cfg.Sampler = "NotASampler" },
+			contains: "sampler must be set to one of the following",
+		},
+		{
+			name:     "missing key fields",
+			modify:   func(cfg *Config) { cfg.KeyFields = nil },
+			contains: "at least one attribute",
+		},
+		{
+			name:     "EMADynamicSampler without goal",
+			modify:   func(cfg *Config) { cfg.GoalSampleRate = 0 },
+			contains: "EMADynamicSampler goal_sample_rate must be set",
+		},
+		{
+			name:     "EMAThroughputSampler without goal",
+			modify:   func(cfg *Config) { cfg.Sampler = EMAThroughputSampler },
+			contains: "EMAThroughputSampler goal_throughput_per_second must be set",
+		},
+		{
+			name:     "EMA sampler with negative adjustment interval",
+			modify:   func(cfg *Config) { cfg.AdjustmentInterval = -time.Second },
+			contains: "adjustment_interval must not be negative",
+		},
+		{
+			name:     "EMA sampler with weight above 1",
+			modify:   func(cfg *Config) { cfg.Weight = 1.5 },
+			contains: "weight must be between 0 and 1",
+		},
+		{
+			name:     "EMA sampler with negative age out value",
+			modify:   func(cfg *Config) { cfg.AgeOutValue = -1 },
+			contains: "age_out_value must not be negative",
+		},
+		{
+			name:     "EMA sampler with negative burst multiple",
+			modify:   func(cfg *Config) { cfg.BurstMultiple = -2 },
+			contains: "burst_multiple must not be negative",
+		},
+		{
+			name: "EMA throughput sampler with negative max keys",
+			modify: func(cfg *Config) {
+				cfg.Sampler = EMAThroughputSampler
+				cfg.GoalThroughputPerSecond = 10
+				cfg.MaxKeys = -1
+			},
+			contains: "max_keys must not be negative",
+		},
+		{
+			name:     "AvgSampleRateSampler without goal",
+			modify:   func(cfg *Config) { cfg.Sampler = AvgSampleRateSampler },
+			contains: "avg_sample_rate goal_sample_rate must be set",
+		},
+		{
+			name: "AvgSampleWithMinSampler with negative minimum",
+			modify: func(cfg *Config) {
+				cfg.Sampler = AvgSampleWithMinSampler
+				cfg.AvgSampleWithMin = AvgSampleWithMinConfig{GoalSampleRate: 10, MinEventsPerSecond: -1}
+			},
+			contains: "avg_sample_with_min min_events_per_second must not be negative",
+		},
+		{
+			name:     "TotalThroughputSampler without goal",
+			modify:   func(cfg *Config) { cfg.Sampler = TotalThroughputSampler },
+			contains: "total_throughput goal_throughput_per_second must be set",
+		},
+		{
+			name:     "PerKeyThroughputSampler without goal",
+			modify:   func(cfg *Config) { cfg.Sampler = PerKeyThroughputSampler },
+			contains: "per_key_throughput per_key_throughput_per_second must be set",
+		},
+		{
+			name: "OnlyOnceSampler with negative clear frequency",
+			modify: func(cfg *Config) {
+				cfg.Sampler = OnlyOnceSampler
+				cfg.OnlyOnce.ClearFrequency = -time.Second
+			},
+			contains: "only_once clear_frequency must not be negative",
+		},
+		{
+			name: "StaticSampler with zero rate",
+			modify: func(cfg *Config) {
+				cfg.Sampler = StaticSampler
+				cfg.Static.Rates = map[string]int{"audit": 0}
+			},
+			contains: `static rate for key "audit" must be greater than 0`,
+		},
+		{
+			name: "WindowedThroughputSampler with short lookback",
+			modify: func(cfg *Config) {
+				cfg.Sampler = WindowedThroughputSampler
+				cfg.WindowedThroughput = WindowedThroughputConfig{
+					GoalThroughputPerSecond: 1,
+					UpdateFrequency:         10 * time.Second,
+					LookbackFrequency:       time.Second,
+				}
+			},
+			contains: "windowed_throughput lookback_frequency must not be less than update_frequency",
+		},
+		{
+			name: "named sampler with invalid config",
+			modify: func(cfg *Config) {
+				cfg.Samplers = map[string]SamplerConfig{"audit": {Sampler: StaticSampler}}
+			},
+			contains: "samplers::audit: Must set at least one attribute",
+		},
+		{
+			name: "named sampler using the reserved default name",
+			modify: func(cfg *Config) {
+				cfg.Samplers = map[string]SamplerConfig{"default": {Sampler: StaticSampler, KeyFields: []string{"key1"}}}
+			},
+			contains: `samplers::default: the name "default" is reserved`,
+		},
+		{
+			name: "rule with unknown action",
+			modify: func(cfg *Config) {
+				cfg.Rules = []RuleConfig{{Name: "bad", Action: "explode"}}
+			},
+			contains: "rules[0]: action must be one of the following",
+		},
+		{
+			name: "sample_rate rule without rate",
+			modify: func(cfg *Config) {
+				cfg.Rules = []RuleConfig{{Name: "debug", Action: RuleActionSampleRate}}
+			},
+			contains: "sample_rate must be set and greater than 0",
+		},
+		{
+			name: "sampler rule with undefined sampler",
+			modify: func(cfg *Config) {
+				cfg.Rules = []RuleConfig{{Name: "audit", Action: RuleActionSampler, Sampler: "audit"}}
+			},
+			contains: `sampler "audit" is not defined`,
+		},
+		{
+			name: "partitions without partition_by",
+			modify: func(cfg *Config) {
+				cfg.Partitions = map[string]PartitionConfig{"checkout": {GoalSampleRate: 5}}
+			},
+			contains: "partition_by must be set to use partitions",
+		},
+		{
+			name: "partition override without the sampler goal",
+			modify: func(cfg *Config) {
+				cfg.PartitionBy = "service.name"
+				cfg.Partitions = map[string]PartitionConfig{"checkout": {GoalThroughputPerSecond: 5}}
+			},
+			contains: "partitions::checkout goal_sample_rate must be set for EMADynamicSampler",
+		},
+		{
+			name: "partition override for a sampler without a goal",
+			modify: func(cfg *Config) {
+				cfg.Sampler = StaticSampler
+				cfg.PartitionBy = "service.name"
+				cfg.Partitions = map[string]PartitionConfig{"checkout": {GoalSampleRate: 5}}
+			},
+			contains: "partitions::checkout StaticSampler has no goal to override",
+		},
+		{
+			name:     "negative partition idle timeout",
+			modify:   func(cfg *Config) { cfg.PartitionIdleTimeout = -time.Second },
+			contains: "partition_idle_timeout must not be negative",
+		},
+		{
+			name:     "negative max partitions",
+			modify:   func(cfg *Config) { cfg.MaxPartitions = -1 },
+			contains: "max_partitions must not be negative",
+		},
+		{
+			name:     "negative state snapshot interval",
+			modify:   func(cfg *Config) { cfg.StateSnapshotInterval = -time.Second },
+			contains: "state_snapshot_interval must not be negative",
+		},
+		{
+			name:     "negative key limit",
+			modify:   func(cfg *Config) { cfg.KeyLimit = -1 },
+			contains: "key_limit must not be negative",
+		},
+		{
+			name:     "negative key limit interval",
+			modify:   func(cfg *Config) { cfg.KeyLimitInterval = -time.Second },
+			contains: "key_limit_interval must not be negative",
+		},
+		{
+			name:     "negative min sample rate",
+			modify:   func(cfg *Config) { cfg.MinSampleRate = -1 },
+			contains: "min_sample_rate and max_sample_rate must not be negative",
+		},
+		{
+			name: "min sample rate above max sample rate",
+			modify: func(cfg *Config) {
+				cfg.MinSampleRate = 10
+				cfg.MaxSampleRate = 5
+			},
+			contains: "min_sample_rate must not be greater than max_sample_rate",
+		},
+		{
+			name: "sample rate clamp without matcher",
+			modify: func(cfg *Config) {
+				cfg.SampleRateClamps = []SampleRateClampConfig{{MaxSampleRate: 5}}
+			},
+			contains: "sample_rate_clamps[0]: key or conditions must be set",
+		},
+		{
+			name: "sample rate clamp without limit",
+			modify: func(cfg *Config) {
+				cfg.SampleRateClamps = []SampleRateClampConfig{{Key: "payments"}}
+			},
+			contains: "sample_rate_clamps[0]: min_sample_rate or max_sample_rate must be set",
+		},
+		{
+			name: "sample rate clamp min above max",
+			modify: func(cfg *Config) {
+				cfg.SampleRateClamps = []SampleRateClampConfig{{Key: "payments", MinSampleRate: 10, MaxSampleRate: 5}}
+			},
+			contains: "sample_rate_clamps[0]: min_sample_rate must not be greater than max_sample_rate",
+		},
+		{
+			name: "budget without events",
+			modify: func(cfg *Config) {
+				cfg.Budget.UsageSourceID = &component.ID{}
+			},
+			contains: "budget events must be set and greater than 0",
+		},
+		{
+			name: "unknown budget period",
+			modify: func(cfg *Config) {
+				cfg.Budget = BudgetConfig{UsageSourceID: &component.ID{}, Events: 10, Period: "weekly"}
+			},
+			contains: "budget period must be one of the following: daily, monthly",
+		},
+		{
+			name: "negative budget adjustment interval",
+			modify: func(cfg *Config) {
+				cfg.Budget = BudgetConfig{UsageSourceID: &component.ID{}, Events: 10, AdjustmentInterval: -time.Second}
+			},
+			contains: "budget adjustment_interval must not be negative",
+		},
+		{
+			name: "budget and goal source",
+			modify: func(cfg *Config) {
+				cfg.Budget = BudgetConfig{UsageSourceID: &component.ID{}, Events: 10}
+				cfg.GoalSourceID = &component.ID{}
+			},
+			contains: "budget and goal_source cannot both be set",
+		},
+		{
+			name: "budget with static sampler",
+			modify: func(cfg *Config) {
+				cfg.Budget = BudgetConfig{UsageSourceID: &component.ID{}, Events: 10}
+				cfg.Sampler = StaticSampler
+			},
+			contains: "budget cannot be used with StaticSampler, which has no goal",
+		},
+		{
+			name: "schedule with invalid cron",
+			modify: func(cfg *Config) {
+				cfg.Schedules = []ScheduleConfig{{Cron: "0 25 * * *", Duration: time.Hour, GoalSampleRate: 2}}
+			},
+			contains: "schedules[0]: hour 25 is not between 0 and 23",
+		},
+		{
+			name: "schedule duration in seconds",
+			modify: func(cfg *Config) {
+				cfg.Schedules = []ScheduleConfig{{Cron: "0 9 * * *", Duration: 90 * time.Second, GoalSampleRate: 2}}
+			},
+			contains: "schedules[0]: duration must be a positive whole number of minutes",
+		},
+		{
+			name: "schedule with unknown time zone",
+			modify: func(cfg *Config) {
+				cfg.Schedules = []ScheduleConfig{{Cron: "0 9 * * *", Duration: time.Hour, TimeZone: "Mars/Olympus", GoalSampleRate: 2}}
+			},
+			contains: "schedules[0]: time_zone: unknown time zone Mars/Olympus",
+		},
+		{
+			name: "schedule without the goal of the sampler",
+			modify: func(cfg *Config) {
+				cfg.Schedules = []ScheduleConfig{{Cron: "0 9 * * *", Duration: time.Hour, GoalThroughputPerSecond: 100}}
+			},
+			contains: "schedules[0]: goal_sample_rate must be set and greater than 0 for EMADynamicSampler",
+		},
+		{
+			name: "schedule with a fractional goal for a whole number sampler",
+			modify: func(cfg *Config) {
+				cfg.Sampler = EMAThroughputSampler
+				cfg.GoalThroughputPerSecond = 100
+				cfg.Schedules = []ScheduleConfig{{Cron: "0 9 * * *", Duration: time.Hour, GoalThroughputPerSecond: 0.5}}
+			},
+			contains: "schedules[0]: goal_throughput_per_second must be a whole number for EMAThroughputSampler",
+		},
+		{
+			name: "schedules and budget",
+			modify: func(cfg *Config) {
+				cfg.Budget = BudgetConfig{UsageSourceID: &component.ID{}, Events: 10}
+				cfg.Schedules = []ScheduleConfig{{Cron: "0 9 * * *", Duration: time.Hour, GoalSampleRate: 2}}
+			},
+			contains: "schedules and budget cannot both be set",
+		},
+		{
+			name: "schedules with static sampler",
+			modify: func(cfg *Config) {
+				cfg.Sampler = StaticSampler
+				cfg.Schedules = []ScheduleConfig{{Cron: "0 9 * * *", Duration: time.Hour, GoalSampleRate: 2}}
+			},
+			contains: "schedules cannot be used with StaticSampler, which has no goal",
+		},
+		{
+			name:     "debug endpoint without port",
+			modify:   func(cfg *Config) { cfg.Debug.Endpoint = "localhost" },
+			contains: "debug endpoint: address localhost: missing port in address",
+		},
+		{
+			name:     "negative tail sampling decision wait",
+			modify:   func(cfg *Config) { cfg.TailSampling.DecisionWait = -time.Second },
+			contains: "tail_sampling decision_wait must not be negative",
+		},
+		{
+			name:     "negative tail sampling max spans",
+			modify:   func(cfg *Config) { cfg.TailSampling.MaxSpans = -1 },
+			contains: "tail_sampling max_traces and max_spans must not be negative",
+		},
+		{
+			name:     "negative tail sampling decision cache size",
+			modify:   func(cfg *Config) { cfg.TailSampling.DecisionCacheSize = -1 },
+			contains: "tail_sampling decision_cache_size must not be negative",
+		},
+		{
+			name:     "negative shards",
+			modify:   func(cfg *Config) { cfg.Shards = -1 },
+			contains: "shards must not be negative",
+		},
+		{
+			name:     "unknown throughput unit",
+			modify:   func(cfg *Config) { cfg.ThroughputUnit = "kilobytes" },
+			contains: "throughput_unit must be one of the following: records, bytes",
+		},
+		{
+			name:     "throughput in bytes with a sample rate sampler",
+			modify:   func(cfg *Config) { cfg.ThroughputUnit = ThroughputUnitBytes },
+			contains: "throughput_unit bytes cannot be used with EMADynamicSampler, which has no throughput goal",
+		},
+		{
+			name: "throughput in bytes with a named sample rate sampler",
+			modify: func(cfg *Config) {
+				cfg.Sampler = EMAThroughputSampler
+				cfg.GoalThroughputPerSecond = 1000
+				cfg.ThroughputUnit = ThroughputUnitBytes
+				cfg.Samplers = map[string]SamplerConfig{
+					"errors": {Sampler: EMADynamicSampler, KeyFields: []string{"key1"}, GoalSampleRate: 10},
+				}
+			},
+			contains: "samplers::errors: throughput_unit bytes cannot be used with EMADynamicSampler, which has no throughput goal",
+		},
+		{
+			name:     "log template similarity threshold above 1",
+			modify:   func(cfg *Config) { cfg.LogTemplates.SimilarityThreshold = 1.5 },
+			contains: "log_templates similarity_threshold must be between 0 and 1",
+		},
+		{
+			name:     "negative max log templates",
+			modify:   func(cfg *Config) { cfg.LogTemplates.MaxTemplates = -1 },
+			contains: "log_templates max_templates must not be negative",
+		},
+		{
+			name:     "negative summaries interval",
+			modify:   func(cfg *Config) { cfg.Summaries.Interval = -time.Second },
+			contains: "summaries interval must not be negative",
+		},
+	}
 
-// 			factory := NewFactory()
-// 			factories.Processors[metadata.Type] = factory
-// 			// https://github.com/open-telemetry/opentelemetry-collector-contrib/issues/33594
-// 			// nolint:staticcheck
-// 			_, err = otelcoltest.LoadConfigAndValidate(filepath.Join("testdata", test.file), factories)
-// 			require.ErrorContains(t, err, test.contains)
-// 		})
-// 	}
-// }
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := createDefaultConfig().(*Config)
+			tt.modify(cfg)
+			assert.ErrorContains(t, xconfmap.Validate(cfg), tt.contains)
+		})
+	}
+}
@@ -0,0 +1,183 @@
+package dynamicsamplingprocessor
+
+import (
+	"fmt"
+	"math/bits"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cronSchedule is a parsed five field cron expression: minute, hour, day of month, month and day of week. Each field
+// is a bit set of the values it matches.
+type cronSchedule struct {
+	minute, hour, dom, month, dow uint64
+
+	// domStar and dowStar record whether the day fields start with *, because like in Vixie cron a time matches when
+	// either day field matches if both are restricted. As in Vixie cron, a stepped */n field counts as *.
+	domStar, dowStar bool
+}
+
+// cronField describes the values of a cron field.
+type cronField struct {
+	name     string
+	min, max int
+	names    []string
+}
+
+var (
+	cronMinute = cronField{name: "minute", min: 0, max: 59}
+	cronHour   = cronField{name: "hour", min: 0, max: 23}
+	cronDom    = cronField{name: "day of month", min: 1, max: 31}
+	cronMonth  = cronField{name: "month", min: 1, max: 12, names: []string{
+		"jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov", "dec",
+	}}
+	// Sunday can be given as 0 or 7.
+	cronDow = cronField{name: "day of week", min: 0, max: 7, names: []string{
+		"sun", "mon", "tue", "wed", "thu", "fri", "sat",
+	}}
+)
+
+// parseCron parses a cron expression of five space separated fields. Each field is *, a value, a range a-b or a
+// comma separated list of them, optionally followed by a step /n. Months and days of the week can also be given by
+// their three letter English names.
+func parseCron(spec string) (*cronSchedule, error) {
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("cron expression %q must have 5 fields, not %d", spec, len(fields))
+	}
+
+	var c cronSchedule
+	var err error
+	if c.minute, err = cronMinute.parse(fields[0]); err != nil {
+		return nil, err
+	}
+	if c.hour, err = cronHour.parse(fields[1]); err != nil {
+		return nil, err
+	}
+	if c.dom, err = cronDom.parse(fields[2]); err != nil {
+		return nil, err
+	}
+	if c.month, err = cronMonth.parse(fields[3]); err != nil {
+		return nil, err
+	}
+	if c.dow, err = cronDow.parse(fields[4]); err != nil {
+		return nil, err
+	}
+	if c.dow&(1<<7) != 0 {
+		c.dow |= 1
+	}
+	c.domStar = strings.HasPrefix(fields[2], "*")
+	c.dowStar = strings.HasPrefix(fields[4], "*")
+	return &c, nil
+}
+
+func (f cronField) parse(field string) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		rng, stepStr, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			var err error
+			if step, err = strconv.Atoi(stepStr); err != nil || step <= 0 {
+				return 0, fmt.Errorf("invalid step %q in %s field", stepStr, f.name)
+			}
+		}
+
+		lo, hi := f.min, f.max
+		if rng != "*" {
+			loStr, hiStr, isRange := strings.Cut(rng, "-")
+			var err error
+			if lo, err = f.value(loStr); err != nil {
+				return 0, err
+			}
+			hi = lo
+			if isRange {
+				if hi, err = f.value(hiStr); err != nil {
+					return 0, err
+				}
+				if hi < lo {
+					return 0, fmt.Errorf("invalid range %q in %s field", rng, f.name)
+				}
+			} else if hasStep {
+				hi = f.max
+			}
+		}
+
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << v
+		}
+	}
+	return bits, nil
+}
+
+func (f cronField) value(s string) (int, error) {
+	for i, name := range f.names {
+		if strings.EqualFold(s, name) {
+			return i + f.min, nil
+		}
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value %q in %s field", s, f.name)
+	}
+	if v < f.min || v > f.max {
+		return 0, fmt.Errorf("%s %d is not between %d and %d", f.name, v, f.min, f.max)
+	}
+	return v, nil
+}
+
+// matches returns whether the minute of t, in the location of t, matches the schedule.
+func (c *cronSchedule) matches(t time.Time) bool {
+	return c.month&(1<<int(t.Month())) != 0 && c.dayMatches(t) && c.hour&(1<<t.Hour()) != 0 &&
+		c.minute&(1<<t.Minute()) != 0
+}
+
+// dayMatches returns whether the day of t matches the day of month and day of week fields.
+func (c *cronSchedule) dayMatches(t time.Time) bool {
+	domMatch := c.dom&(1<<t.Day()) != 0
+	dowMatch := c.dow&(1<<int(t.Weekday())) != 0
+	if c.domStar || c.dowStar {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
+
+// lastMatch returns the latest minute at or before t, and after t minus d, that matches the schedule, or false if
+// there is none. It works back field by field, skipping whole months, days and hours that do not match, rather than
+// checking every minute.
+func (c *cronSchedule) lastMatch(t time.Time, d time.Duration) (time.Time, bool) {
+	m := t.Truncate(time.Minute)
+	for t.Sub(m) < d {
+		var prev time.Time
+		switch {
+		case c.month&(1<<int(m.Month())) == 0:
+			// the last minute of the previous month
+			prev = time.Date(m.Year(), m.Month(), 1, 0, 0, 0, 0, m.Location()).Add(-time.Minute)
+		case !c.dayMatches(m):
+			// the last minute of the previous day
+			prev = time.Date(m.Year(), m.Month(), m.Day(), 0, 0, 0, 0, m.Location()).Add(-time.Minute)
+		case c.hour&(1<<m.Hour()) == 0:
+			// the last minute of the previous hour
+			prev = m.Add(-time.Duration(m.Minute()+1) * time.Minute)
+		default:
+			// the latest matching minute of this hour, or else the last minute of the previous hour
+			earlier := c.minute & (1<<(m.Minute()+1) - 1)
+			if earlier == 0 {
+				prev = m.Add(-time.Duration(m.Minute()+1) * time.Minute)
+				break
+			}
+			match := m.Add(-time.Duration(m.Minute()-(63-bits.LeadingZeros64(earlier))) * time.Minute)
+			if t.Sub(match) >= d {
+				return time.Time{}, false
+			}
+			return match, true
+		}
+		if !prev.Before(m) {
+			// time.Date can resolve a time in a daylight saving transition to a later one, so always move back
+			prev = m.Add(-time.Minute)
+		}
+		m = prev
+	}
+	return time.Time{}, false
+}
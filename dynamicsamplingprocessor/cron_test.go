@@ -0,0 +1,168 @@
+package dynamicsamplingprocessor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCronMatches(t *testing.T) {
+	// 2026-10-18 is a Sunday
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2026, time.October, day, hour, minute, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		spec     string
+		matching []time.Time
+		others   []time.Time
+	}{
+		{
+			spec:     "* * * * *",
+			matching: []time.Time{at(18, 0, 0), at(19, 23, 59)},
+		},
+		{
+			spec:     "0 9 * * mon-fri",
+			matching: []time.Time{at(19, 9, 0), at(23, 9, 0)},
+			others:   []time.Time{at(18, 9, 0), at(24, 9, 0), at(19, 9, 1), at(19, 10, 0)},
+		},
+		{
+			spec:     "*/15 8-10 * * *",
+			matching: []time.Time{at(18, 8, 0), at(18, 10, 45)},
+			others:   []time.Time{at(18, 8, 10), at(18, 11, 0)},
+		},
+		{
+			spec:     "30 22 1,15 oct 7",
+			matching: []time.Time{at(1, 22, 30), at(15, 22, 30), at(18, 22, 30)},
+			others:   []time.Time{at(19, 22, 30)},
+		},
+		{
+			// like Vixie cron, a stepped day field counts as *, so both day fields must match: odd days that are
+			// Mondays
+			spec:     "0 12 */2 * mon",
+			matching: []time.Time{at(5, 12, 0), at(19, 12, 0)},
+			others:   []time.Time{at(12, 12, 0), at(7, 12, 0), at(26, 12, 0)},
+		},
+		{
+			// the first of the month only when it is a Sunday, Tuesday, Thursday or Saturday, so not on Monday June 1
+			spec:     "0 0 1 * */2",
+			matching: []time.Time{time.Date(2026, time.October, 1, 0, 0, 0, 0, time.UTC)},
+			others:   []time.Time{time.Date(2026, time.June, 1, 0, 0, 0, 0, time.UTC), at(4, 0, 0)},
+		},
+		{
+			spec:     "0 0/6 * * *",
+			matching: []time.Time{at(18, 0, 0), at(18, 6, 0), at(18, 18, 0)},
+			others:   []time.Time{at(18, 3, 0)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			c, err := parseCron(tt.spec)
+			require.NoError(t, err)
+			for _, m := range tt.matching {
+				assert.True(t, c.matches(m), m)
+			}
+			for _, o := range tt.others {
+				assert.False(t, c.matches(o), o)
+			}
+		})
+	}
+}
+
+func TestCronParseErrors(t *testing.T) {
+	tests := []struct {
+		spec     string
+		contains string
+	}{
+		{spec: "0 9 * *", contains: "must have 5 fields, not 4"},
+		{spec: "60 * * * *", contains: "minute 60 is not between 0 and 59"},
+		{spec: "* 10-8 * * *", contains: `invalid range "10-8" in hour field`},
+		{spec: "*/0 * * * *", contains: `invalid step "0" in minute field`},
+		{spec: "* * * foo *", contains: `invalid value "foo" in month field`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			_, err := parseCron(tt.spec)
+			assert.ErrorContains(t, err, tt.contains)
+		})
+	}
+}
+
+func TestCronLastMatch(t *testing.T) {
+	c, err := parseCron("0 9 * * *")
+	require.NoError(t, err)
+
+	now := time.Date(2026, time.October, 18, 12, 30, 15, 0, time.UTC)
+	start, ok := c.lastMatch(now, 4*time.Hour)
+	assert.True(t, ok)
+	assert.Equal(t, time.Date(2026, time.October, 18, 9, 0, 0, 0, time.UTC), start)
+
+	_, ok = c.lastMatch(now, 3*time.Hour)
+	assert.False(t, ok)
+}
+
+// referenceLastMatch is lastMatch done the slow way, checking every minute.
+func referenceLastMatch(c *cronSchedule, t time.Time, d time.Duration) (time.Time, bool) {
+	for m := t.Truncate(time.Minute); t.Sub(m) < d; m = m.Add(-time.Minute) {
+		if c.matches(m) {
+			return m, true
+		}
+	}
+	return time.Time{}, false
+}
+
+func TestCronLastMatchReference(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+
+	specs := []string{
+		"* * * * *",
+		"0 9 * * mon-fri",
+		"*/15 8-10 * * *",
+		"30 22 1,15 oct 7",
+		"0 12 */2 * mon",
+		"0 0 1 * */2",
+		"59 23 31 * *",
+		"0 0 29 feb *",
+		"30 2 * * *",
+		"5,55 1-3 * mar,nov sun",
+	}
+	times := []time.Time{
+		time.Date(2026, time.October, 18, 12, 30, 15, 0, time.UTC),
+		time.Date(2026, time.January, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2028, time.March, 1, 0, 0, 0, 0, time.UTC),
+		// just after the daylight saving transitions in New York
+		time.Date(2026, time.March, 8, 3, 10, 0, 0, newYork),
+		time.Date(2026, time.November, 1, 1, 20, 0, 0, newYork).Add(time.Hour),
+		time.Date(2026, time.November, 2, 0, 0, 0, 0, newYork),
+	}
+	windows := []time.Duration{time.Minute, time.Hour, 25 * time.Hour, 40 * 24 * time.Hour, 400 * 24 * time.Hour}
+
+	for _, spec := range specs {
+		c, err := parseCron(spec)
+		require.NoError(t, err)
+		for _, now := range times {
+			for _, d := range windows {
+				expected, expectedOK := referenceLastMatch(c, now, d)
+				got, ok := c.lastMatch(now, d)
+				require.Equal(t, expectedOK, ok, "%s at %s within %s", spec, now, d)
+				assert.True(t, expected.Equal(got), "%s at %s within %s: %s, not %s", spec, now, d, got, expected)
+			}
+		}
+	}
+}
+
+func BenchmarkCronLastMatch(b *testing.B) {
+	c, err := parseCron("0 0 29 feb *")
+	require.NoError(b, err)
+	now := time.Date(2027, time.October, 18, 12, 30, 0, 0, time.UTC)
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		c.lastMatch(now, 366*24*time.Hour)
+	}
+}
@@ -0,0 +1,263 @@
+package dynamicsamplingprocessor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"html/template"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/collector/component"
+	"go.uber.org/zap"
+)
+
+const (
+	// debugPath is the path the debug page is served at.
+	debugPath = "/debug/dynamicsampler"
+
+	// defaultDebugKeys is the number of keys listed per sampler when the page is not given a limit.
+	defaultDebugKeys = 100
+)
+
+// debugServers holds the debug server of each component ID, shared by its logs and traces processors so that they
+// are shown on one page served on the configured endpoint.
+var debugServers = newSharedComponents[*debugServer]()
+
+// debugSnapshot is the state of the processor shown on the debug page.
+type debugSnapshot struct {
+	Processor string `json:"processor"`
+
+	// Signals are the signals processed by the processor, in the order of their names.
+	Signals []debugSignalSnapshot `json:"signals"`
+}
+
+// debugSignalSnapshot is the state of the logs or traces processor shown on the debug page.
+type debugSignalSnapshot struct {
+	Signal string `json:"signal"`
+
+	// Kept and Dropped are the number of records kept and dropped since the processor started.
+	Kept    int64 `json:"kept"`
+	Dropped int64 `json:"dropped"`
+
+	Samplers []debugSampler `json:"samplers"`
+}
+
+// debugSampler is the state of one sampler shown on the debug page.
+type debugSampler struct {
+	Name                    string  `json:"name"`
+	Sampler                 string  `json:"sampler"`
+	GoalSampleRate          int     `json:"goal_sample_rate,omitempty"`
+	GoalThroughputPerSecond float64 `json:"goal_throughput_per_second,omitempty"`
+	ActiveKeys              int64   `json:"active_keys"`
+
+	// Keys are the keys seen in the last interval or the current one, busiest in the last interval first.
+	Keys []keyStats `json:"keys"`
+}
+
+// debugSamplers is implemented by the deciders of the logs and traces processors.
+type debugSamplers interface {
+	debugSamplers(now time.Time, limit int) []debugSampler
+}
+
+// debugSignal is the part of the debug page contributed by the logs or the traces processor.
+type debugSignal struct {
+	name     string
+	samplers debugSamplers
+
+	kept    atomic.Int64
+	dropped atomic.Int64
+}
+
+func newDebugSignal(name string, samplers debugSamplers) *debugSignal {
+	return &debugSignal{name: name, samplers: samplers}
+}
+
+// record counts kept and dropped records. It does nothing on a nil debugSignal.
+func (s *debugSignal) record(kept, dropped int64) {
+	if s == nil {
+		return
+	}
+	s.kept.Add(kept)
+	s.dropped.Add(dropped)
+}
+
+// debugServer serves a page, in the style of the zpages extension, showing the keys of the samplers, their current
+// sample rates and traffic, and the number of records kept and dropped, for each signal attached to it. The page is
+// served as HTML, or as JSON with ?format=json. The number of keys listed per sampler is set with ?limit=, where 0
+// lists all keys.
+type debugServer struct {
+	id       component.ID
+	endpoint string
+	logger   *zap.Logger
+
+	// mu guards the fields below. The page is served while at least one signal is attached.
+	mu       sync.Mutex
+	signals  []*debugSignal
+	server   *http.Server
+	listener net.Listener
+	wg       sync.WaitGroup
+}
+
+func newDebugServer(cfg *Config, id component.ID, logger *zap.Logger) *debugServer {
+	return &debugServer{
+		id:       id,
+		endpoint: cfg.Debug.Endpoint,
+		logger:   logger,
+	}
+}
+
+// attach adds signal to the page, and starts serving the page if it is the first signal attached. It does nothing
+// when no endpoint is configured.
+func (s *debugServer) attach(signal *debugSignal) error {
+	if s.endpoint == "" {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.server == nil {
+		listener, err := net.Listen("tcp", s.endpoint)
+		if err != nil {
+			return err
+		}
+		mux := http.NewServeMux()
+		mux.HandleFunc(debugPath, s.handle)
+		s.listener = listener
+		s.server = &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+
+		server := s.server
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			if err := server.Serve(listener); !errors.Is(err, http.ErrServerClosed) {
+				s.logger.Error("failed to serve the dynamic sampler debug page", zap.Error(err))
+			}
+		}()
+	}
+	s.signals = append(s.signals, signal)
+	return nil
+}
+
+// detach removes signal from the page, and stops serving the page once no signal is attached.
+func (s *debugServer) detach(ctx context.Context, signal *debugSignal) error {
+	s.mu.Lock()
+	s.signals = slices.DeleteFunc(s.signals, func(attached *debugSignal) bool { return attached == signal })
+	var server *http.Server
+	if len(s.signals) == 0 {
+		server, s.server, s.listener = s.server, nil, nil
+	}
+	s.mu.Unlock()
+
+	if server == nil {
+		return nil
+	}
+	// the page handler takes s.mu, so the server is shut down without holding it
+	err := server.Shutdown(ctx)
+	s.wg.Wait()
+	return err
+}
+
+// snapshot returns the state of the processor at now, with at most limit keys per sampler unless limit is 0.
+func (s *debugServer) snapshot(now time.Time, limit int) debugSnapshot {
+	s.mu.Lock()
+	signals := slices.Clone(s.signals)
+	s.mu.Unlock()
+	slices.SortFunc(signals, func(a, b *debugSignal) int { return strings.Compare(a.name, b.name) })
+
+	snapshot := debugSnapshot{Processor: s.id.String()}
+	for _, signal := range signals {
+		snapshot.Signals = append(snapshot.Signals, debugSignalSnapshot{
+			Signal:   signal.name,
+			Kept:     signal.kept.Load(),
+			Dropped:  signal.dropped.Load(),
+			Samplers: signal.samplers.debugSamplers(now, limit),
+		})
+	}
+	return snapshot
+}
+
+// debugSamplers returns the state of the samplers of d at now, with at most limit keys per sampler unless limit is
+// 0.
+func (d *decider[K]) debugSamplers(now time.Time, limit int) []debugSampler {
+	var samplers []debugSampler
+	for _, ks := range d.samplers {
+		cfg := ks.config()
+		goals := cfg.goals()
+		keys := ks.rates.stats(now)
+		if limit > 0 && len(keys) > limit {
+			keys = keys[:limit]
+		}
+		samplers = append(samplers, debugSampler{
+			Name:                    ks.name,
+			Sampler:                 cfg.Sampler,
+			GoalSampleRate:          goals.GoalSampleRate,
+			GoalThroughputPerSecond: goals.GoalThroughputPerSecond,
+			ActiveKeys:              ks.keyspaceSize(),
+			Keys:                    keys,
+		})
+	}
+	return samplers
+}
+
+func (s *debugServer) handle(w http.ResponseWriter, r *http.Request) {
+	limit := defaultDebugKeys
+	if l := r.URL.Query().Get("limit"); l != "" {
+		var err error
+		if limit, err = strconv.Atoi(l); err != nil || limit < 0 {
+			http.Error(w, "limit must be a number that is not negative", http.StatusBadRequest)
+			return
+		}
+	}
+	snapshot := s.snapshot(time.Now(), limit)
+
+	if r.URL.Query().Get("format") == "json" {
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(snapshot); err != nil {
+			s.logger.Debug("failed to write the dynamic sampler debug page", zap.Error(err))
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := debugPageTemplate.Execute(w, snapshot); err != nil {
+		s.logger.Debug("failed to write the dynamic sampler debug page", zap.Error(err))
+	}
+}
+
+var debugPageTemplate = template.Must(template.New("debug").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<title>{{.Processor}}</title>
+<style>
+body { font-family: sans-serif; }
+table { border-collapse: collapse; margin-bottom: 2em; }
+th, td { border: 1px solid #ccc; padding: 2px 8px; text-align: left; }
+td.number { text-align: right; }
+</style>
+</head>
+<body>
+<h1>{{.Processor}}</h1>
+<p><a href="?format=json">JSON</a></p>
+{{range .Signals}}
+<h2>{{.Signal}}</h2>
+<p>Kept: {{.Kept}}, dropped: {{.Dropped}} since the processor started.</p>
+{{range .Samplers}}
+<h3>Sampler {{.Name}}</h3>
+<p>{{.Sampler}}{{if .GoalSampleRate}}, goal sample rate {{.GoalSampleRate}}{{end}}{{if .GoalThroughputPerSecond}}, goal throughput {{.GoalThroughputPerSecond}}/s{{end}}, {{.ActiveKeys}} active keys</p>
+<table>
+<tr><th>Key</th><th>Sample rate</th><th>Last interval</th><th>Current interval</th></tr>
+{{range .Keys}}<tr><td>{{.Key}}</td><td class="number">{{.SampleRate}}</td><td class="number">{{.LastIntervalCount}}</td><td class="number">{{.CurrentIntervalCount}}</td></tr>
+{{end}}</table>
+{{end}}
+{{end}}
+</body>
+</html>
+`))
@@ -0,0 +1,133 @@
+package dynamicsamplingprocessor
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/collector/consumer/consumertest"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/processor/processortest"
+	"go.uber.org/zap"
+)
+
+func TestDebugServer(t *testing.T) {
+	cfg := createDefaultConfig().(*Config)
+	cfg.Sampler = StaticSampler
+	cfg.KeyFields = []string{"key1"}
+	cfg.Static = StaticConfig{Default: 1, Rates: map[string]int{"noisy": 5}}
+	cfg.Debug.Endpoint = "localhost:0"
+	require.NoError(t, cfg.Validate())
+
+	d := newTestLogDecider(t, cfg)
+	s := newDebugServer(cfg, component.MustNewIDWithName("dynamic_sampler", "test"), zap.NewNop())
+	signal := newDebugSignal("logs", d)
+	require.NoError(t, s.attach(signal))
+	t.Cleanup(func() { require.NoError(t, s.detach(context.Background(), signal)) })
+
+	for _, value := range []string{"noisy", "noisy", "noisy", "<quiet>"} {
+		decideLog(d, func(lr plog.LogRecord) { lr.Attributes().PutStr("key1", value) })
+	}
+	signal.record(3, 1)
+
+	get := func(query string) string {
+		return getDebugPage(t, s.listener.Addr().String(), query)
+	}
+
+	var snapshot debugSnapshot
+	require.NoError(t, json.Unmarshal([]byte(get("?format=json")), &snapshot))
+	assert.Equal(t, "dynamic_sampler/test", snapshot.Processor)
+	require.Len(t, snapshot.Signals, 1)
+	assert.Equal(t, "logs", snapshot.Signals[0].Signal)
+	assert.Equal(t, int64(3), snapshot.Signals[0].Kept)
+	assert.Equal(t, int64(1), snapshot.Signals[0].Dropped)
+	require.Len(t, snapshot.Signals[0].Samplers, 1)
+	sampler := snapshot.Signals[0].Samplers[0]
+	assert.Equal(t, defaultSamplerName, sampler.Name)
+	assert.Equal(t, StaticSampler, sampler.Sampler)
+	assert.Equal(t, []keyStats{
+		{Key: "noisy", SampleRate: 5, CurrentIntervalCount: 3},
+		{Key: "<quiet>", SampleRate: 1, CurrentIntervalCount: 1},
+	}, sampler.Keys)
+
+	require.NoError(t, json.Unmarshal([]byte(get("?format=json&limit=1")), &snapshot))
+	assert.Len(t, snapshot.Signals[0].Samplers[0].Keys, 1)
+
+	page := get("")
+	assert.Contains(t, page, "<td>noisy</td>")
+	assert.Contains(t, page, "&lt;quiet&gt;")
+
+	resp, err := http.Get("http://" + s.listener.Addr().String() + debugPath + "?limit=-1")
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+func TestDebugServerDisabled(t *testing.T) {
+	cfg := createDefaultConfig().(*Config)
+	cfg.KeyFields = []string{"key1"}
+
+	s := newDebugServer(cfg, component.MustNewID("dynamic_sampler"), zap.NewNop())
+	signal := newDebugSignal("logs", newTestLogDecider(t, cfg))
+	require.NoError(t, s.attach(signal))
+	assert.Nil(t, s.listener)
+	require.NoError(t, s.detach(context.Background(), signal))
+}
+
+func TestDebugServerSharedBetweenPipelines(t *testing.T) {
+	cfg := createDefaultConfig().(*Config)
+	cfg.KeyFields = []string{"key1"}
+	cfg.Debug.Endpoint = "localhost:0"
+
+	set := processortest.NewNopSettings(typ)
+	lp, err := NewFactory().CreateLogs(context.Background(), set, cfg, consumertest.NewNop())
+	require.NoError(t, err)
+	tp, err := NewFactory().CreateTraces(context.Background(), set, cfg, consumertest.NewNop())
+	require.NoError(t, err)
+
+	// both processors start on the same endpoint
+	require.NoError(t, lp.Start(context.Background(), componenttest.NewNopHost()))
+	require.NoError(t, tp.Start(context.Background(), componenttest.NewNopHost()))
+
+	require.Contains(t, debugServers.entries, set.ID)
+	s := debugServers.entries[set.ID].value
+	addr := s.listener.Addr().String()
+
+	signals := func() []string {
+		var snapshot debugSnapshot
+		require.NoError(t, json.Unmarshal([]byte(getDebugPage(t, addr, "?format=json")), &snapshot))
+		var names []string
+		for _, signal := range snapshot.Signals {
+			names = append(names, signal.Signal)
+			assert.Len(t, signal.Samplers, 1)
+		}
+		return names
+	}
+	assert.Equal(t, []string{"logs", "traces"}, signals())
+
+	// the page is served until the last processor shuts down
+	require.NoError(t, lp.Shutdown(context.Background()))
+	assert.Equal(t, []string{"traces"}, signals())
+
+	require.NoError(t, tp.Shutdown(context.Background()))
+	assert.NotContains(t, debugServers.entries, set.ID)
+	_, err = http.Get("http://" + addr + debugPath)
+	assert.Error(t, err)
+}
+
+// getDebugPage returns the debug page served on addr with the given query.
+func getDebugPage(t *testing.T, addr, query string) string {
+	resp, err := http.Get("http://" + addr + debugPath + query)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return string(body)
+}
@@ -0,0 +1,492 @@
+package dynamicsamplingprocessor
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"maps"
+	"math"
+	"math/rand"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/open-telemetry/opentelemetry-collector-contrib/pkg/ottl"
+	"go.opentelemetry.io/collector/component"
+	"go.uber.org/zap"
+
+	dynsampler "github.com/honeycombio/dynsampler-go"
+)
+
+// decision is the sampling decision made for a single record, or for all spans of a single trace.
+type decision struct {
+	keep       bool
+	sampleRate int
+
+	// key is the key the sample rate was computed for, and sampler the name of the sampler that computed it. Both
+	// are empty if no sampler computed the rate.
+	key     string
+	sampler string
+
+	// rule is the name of the rule that matched the record, or empty if no rule matched.
+	rule string
+}
+
+// keyedSampler is a dynsampler-go sampler together with the fields and expressions its key is built from. When
+// partition_by is set, partitions holds one sampler per partition and sampler is nil.
+type keyedSampler[K any] struct {
+	name           string
+	partitions     *partitionedSampler
+	keyFields      []string
+	keyExpressions []*ottl.ValueExpression[K]
+
+	// mu guards cfg and sampler, which are replaced when the goals change, and started.
+	mu      sync.RWMutex
+	cfg     SamplerConfig
+	sampler dynsampler.Sampler
+	started bool
+
+	// rates tracks the sample rate of each key for telemetry.
+	rates *keyRates
+
+	// limiter caps the number of distinct keys, or is nil when key_limit is not set.
+	limiter *keyLimiter
+
+	// clamps limits the computed sample rates, or is nil when no limit is configured.
+	clamps *sampleRateClamps[K]
+}
+
+// newKeyedSampler builds the sampler described by cfg. The sampler is started by start. Partitioned samplers
+// start a sampler for each partition as it is first seen.
+func newKeyedSampler[K any](name string, cfg *SamplerConfig, keyExpressions []*ottl.ValueExpression[K]) (*keyedSampler[K], error) {
+	s := &keyedSampler[K]{
+		name:           name,
+		cfg:            *cfg,
+		keyFields:      cfg.KeyFields,
+		keyExpressions: keyExpressions,
+		rates:          newKeyRates(cfg.Shards),
+	}
+	if cfg.KeyLimit > 0 {
+		s.limiter = newKeyLimiter(cfg)
+	}
+	if cfg.PartitionBy != "" {
+		s.partitions = newPartitionedSampler(cfg)
+		return s, nil
+	}
+
+	sampler, err := newSampler(cfg)
+	if err != nil {
+		return nil, err
+	}
+	s.sampler = sampler
+	return s, nil
+}
+
+// start starts the sampler. Partitioned samplers start reaping idle partitions.
+func (s *keyedSampler[K]) start() error {
+	if s.partitions != nil {
+		s.partitions.start()
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.sampler.Start(); err != nil {
+		return fmt.Errorf("failed to start %s: %w", s.cfg.Sampler, err)
+	}
+	s.started = true
+	return nil
+}
+
+// stop stops the sampler, or the samplers of all partitions.
+func (s *keyedSampler[K]) stop() error {
+	if s.partitions != nil {
+		return s.partitions.stop()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.started {
+		return nil
+	}
+	s.started = false
+	return s.sampler.Stop()
+}
+
+// withGoals returns the config of the sampler with its goal changed to the goal in goals.
+func (s *keyedSampler[K]) withGoals(goals Goals) (SamplerConfig, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	switch s.cfg.Sampler {
+	case OnlyOnceSampler, StaticSampler:
+		return SamplerConfig{}, fmt.Errorf("%s has no goal to change", s.cfg.Sampler)
+	}
+	cfg := s.cfg.withGoal(goals.GoalSampleRate, goals.GoalThroughputPerSecond)
+	if err := cfg.validate(); err != nil {
+		return SamplerConfig{}, err
+	}
+	return cfg, nil
+}
+
+// setConfig replaces the config of the sampler with cfg, as returned by withGoals. A running sampler is replaced by
+// one started with the new config and the state of the running sampler, so the rates it has learned for each key
+// are kept. The running sampler is only stopped once its replacement has been swapped in.
+func (s *keyedSampler[K]) setConfig(cfg SamplerConfig) error {
+	if s.partitions != nil {
+		if err := s.partitions.setConfig(cfg); err != nil {
+			return err
+		}
+		s.mu.Lock()
+		s.cfg = cfg
+		s.mu.Unlock()
+		return nil
+	}
+
+	s.mu.Lock()
+	var (
+		sampler dynsampler.Sampler
+		err     error
+	)
+	if s.started {
+		sampler, err = replaceSampler(s.sampler, &cfg)
+	} else {
+		sampler, err = newSampler(&cfg)
+	}
+	if err != nil {
+		s.mu.Unlock()
+		return err
+	}
+	old, started := s.sampler, s.started
+	s.sampler = sampler
+	s.cfg = cfg
+	s.mu.Unlock()
+
+	if started {
+		_ = old.Stop()
+	}
+	return nil
+}
+
+// getSampler returns the current sampler of a sampler that is not partitioned.
+func (s *keyedSampler[K]) getSampler() dynsampler.Sampler {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.sampler
+}
+
+// sampleRate returns the sample rate for the record described by tCtx and lookup, and the key it was computed
+// for. The record counts as count events towards the sampler's traffic.
+func (s *keyedSampler[K]) sampleRate(ctx context.Context, tCtx K, lookup fieldLookup, count int, logger *zap.Logger) (int, string) {
+	now := time.Now()
+	var sampler dynsampler.Sampler
+	if s.partitions == nil {
+		sampler = s.getSampler()
+	} else {
+		value := s.partitions.partitionValue(lookup)
+		var err error
+		sampler, err = s.partitions.get(value, now)
+		if err != nil {
+			logger.Warn("failed to start partition sampler, keeping record", zap.String("partition", value), zap.Error(err))
+			return 1, ""
+		}
+	}
+
+	key := s.makeKey(ctx, tCtx, lookup, logger)
+	if s.limiter != nil {
+		var warn bool
+		key, warn = s.limiter.apply(key, now)
+		if warn {
+			logger.Warn("dynamic sampler key limit reached, sampling new keys as the overflow key until the limit resets",
+				zap.String("sampler", s.name),
+				zap.Int("key_limit", s.limiter.limit),
+				zap.Duration("key_limit_interval", s.limiter.interval))
+		}
+	}
+	sampleRate := s.clamps.apply(ctx, tCtx, key, getSampleRate(sampler, key, count))
+	if s.rates != nil {
+		s.rates.record(key, sampleRate, now)
+	}
+	return sampleRate, key
+}
+
+// makeKey builds the key of the record described by tCtx and lookup from the key fields and key expressions.
+func (s *keyedSampler[K]) makeKey(ctx context.Context, tCtx K, lookup fieldLookup, logger *zap.Logger) string {
+	b := getKeyBuilder()
+	defer b.release()
+	b.addFields(s.keyFields, lookup)
+	addKeyExpressions(ctx, b, s.keyExpressions, tCtx, logger)
+	return b.String()
+}
+
+// saveState returns the state of the sampler, or nil if the sampler has no state to save.
+func (s *keyedSampler[K]) saveState() ([]byte, error) {
+	if s.partitions != nil {
+		return s.partitions.saveState()
+	}
+	return s.getSampler().SaveState()
+}
+
+// loadState restores the state returned by saveState.
+func (s *keyedSampler[K]) loadState(state []byte) error {
+	if s.partitions != nil {
+		return s.partitions.loadState(state)
+	}
+	return loadSamplerState(s.getSampler(), state)
+}
+
+// overflows returns the number of records given the overflow key since the sampler was created.
+func (s *keyedSampler[K]) overflows() int64 {
+	if s.limiter == nil {
+		return 0
+	}
+	return s.limiter.overflows.Load()
+}
+
+// config returns the current config of the sampler.
+func (s *keyedSampler[K]) config() SamplerConfig {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.cfg
+}
+
+// keyspaceSize returns the number of keys tracked by the sampler, summed over all partitions.
+func (s *keyedSampler[K]) keyspaceSize() int64 {
+	if s.partitions != nil {
+		return s.partitions.keyspaceSize()
+	}
+	return s.getSampler().GetMetrics("")["keyspace_size"]
+}
+
+// rule is a parsed RuleConfig. A rule without conditions has nil conditions and matches every record.
+type rule[K any] struct {
+	name       string
+	conditions *ottl.ConditionSequence[K]
+	action     RuleAction
+	sampleRate int
+	sampler    *keyedSampler[K]
+}
+
+// decider makes sampling decisions. Records are checked against the rules in order, and records that match no
+// rule are sampled by the default sampler.
+type decider[K any] struct {
+	rules   []rule[K]
+	sampler *keyedSampler[K]
+
+	// samplers holds the default sampler followed by the named samplers.
+	samplers []*keyedSampler[K]
+
+	// hashField is the field deterministic decisions are derived from, or empty for random decisions.
+	hashField string
+
+	logger *zap.Logger
+}
+
+// newDecider parses the rules, key expressions and sample rate clamps in the config using the given OTTL parser and
+// builds the configured samplers.
+func newDecider[K any](cfg *Config, parser ottl.Parser[K], set component.TelemetrySettings) (*decider[K], error) {
+	d := &decider[K]{logger: set.Logger}
+	if cfg.Deterministic.Enabled {
+		d.hashField = cfg.Deterministic.Field
+		if d.hashField == "" {
+			d.hashField = traceIDField
+		}
+	}
+
+	keyExpressions, err := parseKeyExpressions(parser, cfg.KeyExpressions)
+	if err != nil {
+		return nil, err
+	}
+
+	namedKeyExpressions := make(map[string][]*ottl.ValueExpression[K], len(cfg.Samplers))
+	for name, samplerCfg := range cfg.Samplers {
+		namedKeyExpressions[name], err = parseKeyExpressions(parser, samplerCfg.KeyExpressions)
+		if err != nil {
+			return nil, fmt.Errorf("sampler %q: %w", name, err)
+		}
+	}
+
+	d.rules = make([]rule[K], 0, len(cfg.Rules))
+	for i, ruleCfg := range cfg.Rules {
+		r := rule[K]{
+			name:       ruleCfg.Name,
+			action:     ruleCfg.Action,
+			sampleRate: ruleCfg.SampleRate,
+		}
+		if len(ruleCfg.Conditions) > 0 {
+			conditions, err := parser.ParseConditions(ruleCfg.Conditions)
+			if err != nil {
+				return nil, fmt.Errorf("rule %d %q: %w", i, ruleCfg.Name, err)
+			}
+			sequence := ottl.NewConditionSequence(conditions, set,
+				ottl.WithLogicOperation[K](ottl.And),
+				ottl.WithConditionSequenceErrorMode[K](ottl.IgnoreError))
+			r.conditions = &sequence
+		}
+		d.rules = append(d.rules, r)
+	}
+
+	d.sampler, err = newKeyedSampler(defaultSamplerName, &cfg.SamplerConfig, keyExpressions)
+	if err != nil {
+		return nil, err
+	}
+	d.samplers = append(d.samplers, d.sampler)
+
+	namedSamplers := make(map[string]*keyedSampler[K], len(cfg.Samplers))
+	for _, name := range slices.Sorted(maps.Keys(cfg.Samplers)) {
+		samplerCfg := cfg.Samplers[name]
+		namedSamplers[name], err = newKeyedSampler(name, &samplerCfg, namedKeyExpressions[name])
+		if err != nil {
+			return nil, fmt.Errorf("sampler %q: %w", name, err)
+		}
+		d.samplers = append(d.samplers, namedSamplers[name])
+	}
+	clamps, err := newSampleRateClamps(cfg, parser, set)
+	if err != nil {
+		return nil, err
+	}
+	for _, sampler := range d.samplers {
+		sampler.clamps = clamps
+	}
+
+	for i, ruleCfg := range cfg.Rules {
+		if ruleCfg.Action == RuleActionSampler {
+			d.rules[i].sampler = namedSamplers[ruleCfg.Sampler]
+		}
+	}
+
+	return d, nil
+}
+
+// start starts all samplers. It is called after any saved state has been restored.
+func (d *decider[K]) start() error {
+	for _, sampler := range d.samplers {
+		if err := sampler.start(); err != nil {
+			return fmt.Errorf("sampler %q: %w", sampler.name, err)
+		}
+	}
+	return nil
+}
+
+// stop stops all samplers.
+func (d *decider[K]) stop() error {
+	var errs error
+	for _, sampler := range d.samplers {
+		errs = errors.Join(errs, sampler.stop())
+	}
+	return errs
+}
+
+// setGoals changes the goals of the samplers named in goals. The default sampler is named "default". No goal is
+// changed if goals names an unknown sampler or holds an invalid goal.
+func (d *decider[K]) setGoals(goals map[string]Goals) error {
+	samplers := make(map[string]*keyedSampler[K], len(goals))
+	cfgs := make(map[string]SamplerConfig, len(goals))
+	for name, goal := range goals {
+		i := slices.IndexFunc(d.samplers, func(s *keyedSampler[K]) bool { return s.name == name })
+		if i < 0 {
+			return fmt.Errorf("unknown sampler %q", name)
+		}
+		cfg, err := d.samplers[i].withGoals(goal)
+		if err != nil {
+			return fmt.Errorf("sampler %q: %w", name, err)
+		}
+		samplers[name] = d.samplers[i]
+		cfgs[name] = cfg
+	}
+
+	var errs error
+	for name, goal := range goals {
+		if err := samplers[name].setConfig(cfgs[name]); err != nil {
+			errs = errors.Join(errs, fmt.Errorf("sampler %q: %w", name, err))
+			continue
+		}
+		d.logger.Info("changed dynamic sampler goals",
+			zap.String("sampler", name),
+			zap.Int("goal_sample_rate", goal.GoalSampleRate),
+			zap.Float64("goal_throughput_per_second", goal.GoalThroughputPerSecond))
+	}
+	return errs
+}
+
+// decide returns the sampling decision for the record described by tCtx and lookup. The record counts as count
+// events towards the sampler's traffic.
+func (d *decider[K]) decide(ctx context.Context, tCtx K, lookup fieldLookup, count int) decision {
+	for _, r := range d.rules {
+		if r.conditions != nil {
+			match, err := r.conditions.Eval(ctx, tCtx)
+			if err != nil {
+				d.logger.Debug("failed to evaluate rule conditions", zap.String("rule", r.name), zap.Error(err))
+				continue
+			}
+			if !match {
+				continue
+			}
+		}
+
+		var dec decision
+		switch r.action {
+		case RuleActionKeep:
+			dec = decision{keep: true, sampleRate: 1}
+		case RuleActionDrop:
+			dec = decision{keep: false}
+		case RuleActionSampleRate:
+			dec = d.sample(r.sampleRate, "", lookup)
+		case RuleActionSampler:
+			dec = d.sampleWith(ctx, r.sampler, tCtx, lookup, count)
+		}
+		dec.rule = r.name
+		return dec
+	}
+
+	return d.sampleWith(ctx, d.sampler, tCtx, lookup, count)
+}
+
+// sampleWith makes the sampling decision for the record at the sample rate computed by the given sampler.
+func (d *decider[K]) sampleWith(ctx context.Context, s *keyedSampler[K], tCtx K, lookup fieldLookup, count int) decision {
+	sampleRate, key := s.sampleRate(ctx, tCtx, lookup, count, d.logger)
+	dec := d.sample(sampleRate, key, lookup)
+	dec.sampler = s.name
+	return dec
+}
+
+// sample makes the sampling decision for the given sample rate, computed for key. When deterministic sampling is
+// enabled and the record has a value for the hash field, the decision is derived from that value. Otherwise it is
+// random.
+func (d *decider[K]) sample(sampleRate int, key string, lookup fieldLookup) decision {
+	if d.hashField != "" {
+		if val, ok := lookup(d.hashField); ok {
+			if s, ok := keyPart(val); ok {
+				return decision{
+					keep:       deterministicKeep(s, sampleRate),
+					sampleRate: sampleRate,
+					key:        key,
+				}
+			}
+		}
+	}
+	decision := sampleWithRate(sampleRate)
+	decision.key = key
+	return decision
+}
+
+// deterministicKeep decides whether to keep a record by comparing a hash of value against the fraction of the
+// hash space that the sample rate keeps. A value kept at one sample rate is also kept at every lower sample rate,
+// so records that share a value but are sampled at different rates are kept together whenever the higher rate
+// keeps them.
+func deterministicKeep(value string, sampleRate int) bool {
+	sum := sha1.Sum([]byte(value))
+	upperBound := math.MaxUint32 / uint32(sampleRate)
+	return binary.BigEndian.Uint32(sum[:4]) <= upperBound
+}
+
+// sampleWithRate makes a random sampling decision for the given sample rate.
+func sampleWithRate(sampleRate int) decision {
+	// example: with sampleRate=10, there is a 10% chance rand.Intn(10) == 0
+	return decision{
+		keep:       rand.Intn(sampleRate) == 0,
+		sampleRate: sampleRate,
+	}
+}
@@ -0,0 +1,194 @@
+package dynamicsamplingprocessor
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"github.com/open-telemetry/opentelemetry-collector-contrib/pkg/ottl/contexts/ottllog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+)
+
+func newTestLogDecider(t *testing.T, cfg *Config) *decider[ottllog.TransformContext] {
+	set := componenttest.NewNopTelemetrySettings()
+	parser, err := newLogParser(set)
+	require.NoError(t, err)
+
+	d, err := newDecider(cfg, parser, set)
+	require.NoError(t, err)
+	require.NoError(t, d.start())
+	t.Cleanup(func() { require.NoError(t, d.stop()) })
+	return d
+}
+
+func decideLog(d *decider[ottllog.TransformContext], modify func(lr plog.LogRecord)) decision {
+	logs := plog.NewLogs()
+	rl := logs.ResourceLogs().AppendEmpty()
+	sl := rl.ScopeLogs().AppendEmpty()
+	lr := sl.LogRecords().AppendEmpty()
+	modify(lr)
+
+	tCtx := ottllog.NewTransformContext(lr, sl.Scope(), rl.Resource(), sl, rl)
+	return d.decide(context.Background(), tCtx, logFieldLookup(rl.Resource(), sl.Scope(), lr), 1)
+}
+
+func TestDeciderRules(t *testing.T) {
+	cfg := createDefaultConfig().(*Config)
+	cfg.Sampler = StaticSampler
+	cfg.KeyFields = []string{"key1"}
+	cfg.Static = StaticConfig{Default: 3}
+	cfg.Samplers = map[string]SamplerConfig{
+		"audit": {
+			Sampler:   StaticSampler,
+			KeyFields: []string{"audit.action"},
+			Static:    StaticConfig{Default: 1, Rates: map[string]int{"read": 7}},
+		},
+	}
+	cfg.Rules = []RuleConfig{
+		{
+			Name:       "errors",
+			Conditions: []string{`severity_number >= SEVERITY_NUMBER_ERROR`},
+			Action:     RuleActionKeep,
+		},
+		{
+			Name:       "health checks",
+			Conditions: []string{`attributes["http.route"] == "/health"`},
+			Action:     RuleActionDrop,
+		},
+		{
+			Name:       "debug",
+			Conditions: []string{`severity_text == "DEBUG"`, `attributes["service.name"] == "checkout"`},
+			Action:     RuleActionSampleRate,
+			SampleRate: 50,
+		},
+		{
+			Name:       "audit",
+			Conditions: []string{`attributes["audit.action"] != nil`},
+			Action:     RuleActionSampler,
+			Sampler:    "audit",
+		},
+	}
+	require.NoError(t, cfg.Validate())
+	d := newTestLogDecider(t, cfg)
+
+	tests := []struct {
+		name     string
+		modify   func(lr plog.LogRecord)
+		expected decision
+		random   bool
+	}{
+		{
+			name: "keep rule",
+			modify: func(lr plog.LogRecord) {
+				lr.SetSeverityNumber(plog.SeverityNumberError)
+				lr.Attributes().PutStr("http.route", "/health")
+			},
+			expected: decision{keep: true, sampleRate: 1, rule: "errors"},
+		},
+		{
+			name:     "drop rule",
+			modify:   func(lr plog.LogRecord) { lr.Attributes().PutStr("http.route", "/health") },
+			expected: decision{keep: false, rule: "health checks"},
+		},
+		{
+			name: "sample rate rule",
+			modify: func(lr plog.LogRecord) {
+				lr.SetSeverityText("DEBUG")
+				lr.Attributes().PutStr("service.name", "checkout")
+			},
+			expected: decision{sampleRate: 50},
+			random:   true,
+		},
+		{
+			name:     "conditions are ANDed",
+			modify:   func(lr plog.LogRecord) { lr.SetSeverityText("DEBUG") },
+			expected: decision{sampleRate: 3},
+			random:   true,
+		},
+		{
+			name:     "sampler rule",
+			modify:   func(lr plog.LogRecord) { lr.Attributes().PutStr("audit.action", "read") },
+			expected: decision{sampleRate: 7},
+			random:   true,
+		},
+		{
+			name:     "no rule matches",
+			modify:   func(plog.LogRecord) {},
+			expected: decision{sampleRate: 3},
+			random:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := decideLog(d, tt.modify)
+			if tt.random {
+				assert.Equal(t, tt.expected.sampleRate, got.sampleRate)
+			} else {
+				assert.Equal(t, tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestDeciderRuleWithoutConditions(t *testing.T) {
+	cfg := createDefaultConfig().(*Config)
+	cfg.Rules = []RuleConfig{{Name: "drop everything", Action: RuleActionDrop}}
+	d := newTestLogDecider(t, cfg)
+
+	assert.Equal(t, decision{keep: false, rule: "drop everything"}, decideLog(d, func(plog.LogRecord) {}))
+}
+
+func TestDeciderInvalidRuleCondition(t *testing.T) {
+	cfg := createDefaultConfig().(*Config)
+	cfg.Rules = []RuleConfig{{Name: "broken", Conditions: []string{`NotAFunction(body)`}, Action: RuleActionKeep}}
+
+	set := componenttest.NewNopTelemetrySettings()
+	parser, err := newLogParser(set)
+	require.NoError(t, err)
+
+	_, err = newDecider(cfg, parser, set)
+	assert.ErrorContains(t, err, `rule 0 "broken"`)
+}
+
+func TestDeterministicKeep(t *testing.T) {
+	assert.True(t, deterministicKeep("anything", 1))
+
+	kept := 0
+	for i := 0; i < 10000; i++ {
+		value := strconv.Itoa(i)
+		if deterministicKeep(value, 10) {
+			kept++
+			// kept at a rate means kept at every lower rate
+			assert.True(t, deterministicKeep(value, 5), value)
+		}
+	}
+	assert.InDelta(t, 1000, kept, 150)
+}
+
+func TestDeciderDeterministic(t *testing.T) {
+	cfg := createDefaultConfig().(*Config)
+	cfg.Sampler = StaticSampler
+	cfg.Static = StaticConfig{Default: 4}
+	cfg.Deterministic = DeterministicConfig{Enabled: true}
+	d := newTestLogDecider(t, cfg)
+
+	for i := 0; i < 100; i++ {
+		traceID := pcommon.TraceID([16]byte{byte(i), 1, 2, 3})
+		expected := decision{
+			keep:       deterministicKeep(traceID.String(), 4),
+			sampleRate: 4,
+			// neither key1 nor key2 is set
+			key:     `\-_\-`,
+			sampler: "default",
+		}
+		for j := 0; j < 3; j++ {
+			got := decideLog(d, func(lr plog.LogRecord) { lr.SetTraceID(traceID) })
+			assert.Equal(t, expected, got)
+		}
+	}
+}
@@ -0,0 +1,23 @@
+package dynamicsamplingprocessor
+
+import "go.opentelemetry.io/collector/pdata/pcommon"
+
+// Attributes that record the sampling decision in dry_run mode.
+const (
+	dryRunKeptAttribute       = "sampler.kept"
+	dryRunSampleRateAttribute = "sampler.sample_rate"
+	dryRunKeyAttribute        = "sampler.key"
+)
+
+// annotateDryRun records the decision made for a record that is kept regardless of it. The sample rate includes
+// the upstream sample rate, as it would have been written to the sample rate attribute. Records dropped by a rule
+// have no sample rate, and records not sampled by a sampler have no key.
+func annotateDryRun(attrs pcommon.Map, d decision, upstreamRate int) {
+	attrs.PutBool(dryRunKeptAttribute, d.keep)
+	if d.sampleRate > 0 {
+		attrs.PutInt(dryRunSampleRateAttribute, int64(d.sampleRate)*int64(upstreamRate))
+	}
+	if d.key != "" {
+		attrs.PutStr(dryRunKeyAttribute, d.key)
+	}
+}
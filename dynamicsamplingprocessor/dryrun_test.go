@@ -0,0 +1,98 @@
+package dynamicsamplingprocessor
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/collector/consumer/consumertest"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.opentelemetry.io/collector/processor/processortest"
+)
+
+func newDryRunConfig() *Config {
+	cfg := createDefaultConfig().(*Config)
+	cfg.Sampler = StaticSampler
+	cfg.KeyFields = []string{"key1"}
+	cfg.Static = StaticConfig{Default: 1_000_000}
+	cfg.DryRun = true
+	return cfg
+}
+
+func TestLogsProcessorDryRun(t *testing.T) {
+	cfg := newDryRunConfig()
+	cfg.Rules = []RuleConfig{{
+		Name:       "drop debug",
+		Conditions: []string{`severity_text == "DEBUG"`},
+		Action:     RuleActionDrop,
+	}}
+
+	sink := new(consumertest.LogsSink)
+	lp, err := NewFactory().CreateLogs(context.Background(), processortest.NewNopSettings(typ), cfg, sink)
+	require.NoError(t, err)
+	require.NoError(t, lp.Start(context.Background(), componenttest.NewNopHost()))
+
+	logs := plog.NewLogs()
+	records := logs.ResourceLogs().AppendEmpty().ScopeLogs().AppendEmpty().LogRecords()
+	sampled := records.AppendEmpty()
+	sampled.Attributes().PutStr("key1", "checkout")
+	sampled.Attributes().PutInt("SampleRate", 2)
+	records.AppendEmpty().SetSeverityText("DEBUG")
+
+	require.NoError(t, lp.ConsumeLogs(context.Background(), logs))
+	require.NoError(t, lp.Shutdown(context.Background()))
+
+	require.Len(t, sink.AllLogs(), 1)
+	got := sink.AllLogs()[0].ResourceLogs().At(0).ScopeLogs().At(0).LogRecords()
+	require.Equal(t, 2, got.Len())
+
+	assert.Equal(t, map[string]any{
+		"key1":                "checkout",
+		"SampleRate":          int64(2),
+		"sampler.kept":        false,
+		"sampler.sample_rate": int64(2_000_000),
+		"sampler.key":         "checkout",
+	}, got.At(0).Attributes().AsRaw())
+	assert.Equal(t, map[string]any{
+		"sampler.kept": false,
+	}, got.At(1).Attributes().AsRaw())
+}
+
+func TestTracesProcessorDryRun(t *testing.T) {
+	cfg := newDryRunConfig()
+	cfg.Static.Rates = map[string]int{"kept": 1}
+
+	sink := new(consumertest.TracesSink)
+	tp, err := NewFactory().CreateTraces(context.Background(), processortest.NewNopSettings(typ), cfg, sink)
+	require.NoError(t, err)
+	require.NoError(t, tp.Start(context.Background(), componenttest.NewNopHost()))
+
+	td := ptrace.NewTraces()
+	ss := td.ResourceSpans().AppendEmpty().ScopeSpans().AppendEmpty()
+	appendSpan(ss, pcommon.TraceID([16]byte{1}), pcommon.SpanID([8]byte{1}), pcommon.NewSpanIDEmpty(), "kept")
+	appendSpan(ss, pcommon.TraceID([16]byte{2}), pcommon.SpanID([8]byte{2}), pcommon.NewSpanIDEmpty(), "dropped")
+
+	require.NoError(t, tp.ConsumeTraces(context.Background(), td))
+	require.NoError(t, tp.Shutdown(context.Background()))
+
+	require.Len(t, sink.AllTraces(), 1)
+	spans := sink.AllTraces()[0].ResourceSpans().At(0).ScopeSpans().At(0).Spans()
+	require.Equal(t, 2, spans.Len())
+
+	assert.Equal(t, map[string]any{
+		"key1":                "kept",
+		"sampler.kept":        true,
+		"sampler.sample_rate": int64(1),
+		"sampler.key":         "kept",
+	}, spans.At(0).Attributes().AsRaw())
+	assert.Equal(t, map[string]any{
+		"key1":                "dropped",
+		"sampler.kept":        false,
+		"sampler.sample_rate": int64(1_000_000),
+		"sampler.key":         "dropped",
+	}, spans.At(1).Attributes().AsRaw())
+}
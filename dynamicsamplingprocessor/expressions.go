@@ -0,0 +1,49 @@
+package dynamicsamplingprocessor
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/open-telemetry/opentelemetry-collector-contrib/pkg/ottl"
+	"github.com/open-telemetry/opentelemetry-collector-contrib/pkg/ottl/contexts/ottllog"
+	"github.com/open-telemetry/opentelemetry-collector-contrib/pkg/ottl/contexts/ottlspan"
+	"github.com/open-telemetry/opentelemetry-collector-contrib/pkg/ottl/ottlfuncs"
+	"go.opentelemetry.io/collector/component"
+	"go.uber.org/zap"
+)
+
+// newLogParser returns an OTTL parser for the log context with the standard converters.
+func newLogParser(set component.TelemetrySettings) (ottl.Parser[ottllog.TransformContext], error) {
+	return ottllog.NewParser(ottlfuncs.StandardConverters[ottllog.TransformContext](), set)
+}
+
+// newSpanParser returns an OTTL parser for the span context with the standard converters.
+func newSpanParser(set component.TelemetrySettings) (ottl.Parser[ottlspan.TransformContext], error) {
+	return ottlspan.NewParser(ottlfuncs.StandardConverters[ottlspan.TransformContext](), set)
+}
+
+func parseKeyExpressions[K any](parser ottl.Parser[K], expressions []string) ([]*ottl.ValueExpression[K], error) {
+	exprs := make([]*ottl.ValueExpression[K], 0, len(expressions))
+	for _, expression := range expressions {
+		expr, err := parser.ParseValueExpression(expression)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse key expression %q: %w", expression, err)
+		}
+		exprs = append(exprs, expr)
+	}
+	return exprs, nil
+}
+
+// addKeyExpressions evaluates the key expressions and adds the string form of each result to the key. Like missing
+// key fields, expressions that fail to evaluate or resolve to nil or a non-scalar value are missing from the key.
+func addKeyExpressions[K any](ctx context.Context, b *keyBuilder, exprs []*ottl.ValueExpression[K], tCtx K, logger *zap.Logger) {
+	for _, expr := range exprs {
+		val, err := expr.Eval(ctx, tCtx)
+		if err != nil {
+			logger.Debug("failed to evaluate key expression", zap.Error(err))
+			b.addMissing()
+			continue
+		}
+		b.addValue(val)
+	}
+}
@@ -0,0 +1,50 @@
+package dynamicsamplingprocessor
+
+import (
+	"context"
+	"testing"
+
+	"github.com/open-telemetry/opentelemetry-collector-contrib/pkg/ottl/contexts/ottllog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/collector/consumer/consumertest"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/processor/processortest"
+	"go.uber.org/zap"
+)
+
+func TestLogKeyExpressions(t *testing.T) {
+	parser, err := newLogParser(componenttest.NewNopTelemetrySettings())
+	require.NoError(t, err)
+
+	exprs, err := parseKeyExpressions(parser, []string{
+		`Substring(attributes["http.response.status_code"], 0, 1)`,
+		`Split(attributes["url.path"], "/")[1]`,
+		`attributes["missing"]`,
+		`severity_number`,
+	})
+	require.NoError(t, err)
+
+	logs := plog.NewLogs()
+	rl := logs.ResourceLogs().AppendEmpty()
+	sl := rl.ScopeLogs().AppendEmpty()
+	lr := sl.LogRecords().AppendEmpty()
+	lr.Attributes().PutStr("http.response.status_code", "503")
+	lr.Attributes().PutStr("url.path", "/checkout/cart/42")
+	lr.SetSeverityNumber(plog.SeverityNumberWarn)
+
+	tCtx := ottllog.NewTransformContext(lr, sl.Scope(), rl.Resource(), sl, rl)
+	b := getKeyBuilder()
+	defer b.release()
+	addKeyExpressions(context.Background(), b, exprs, tCtx, zap.NewNop())
+	assert.Equal(t, `5_checkout_\-_13`, b.String())
+}
+
+func TestInvalidKeyExpression(t *testing.T) {
+	cfg := createDefaultConfig().(*Config)
+	cfg.KeyExpressions = []string{`NotAFunction(body)`}
+
+	_, err := NewFactory().CreateLogs(context.Background(), processortest.NewNopSettings(typ), cfg, consumertest.NewNop())
+	assert.ErrorContains(t, err, `failed to parse key expression "NotAFunction(body)"`)
+}
@@ -14,17 +14,30 @@ func NewFactory() processor.Factory {
 	return processor.NewFactory(
 		component.MustNewType("dynamic_sampler"),
 		createDefaultConfig,
+		processor.WithTraces(createTracesProcessor, component.StabilityLevelDevelopment),
 		processor.WithLogs(createLogsProcessor, component.StabilityLevelDevelopment))
 }
 
 func createDefaultConfig() component.Config {
 	return &Config{
-		Sampler:        EMADynamicSampler,
-		KeyFields:      []string{"key1", "key2"},
-		GoalSampleRate: 10,
+		SamplerConfig: SamplerConfig{
+			Sampler:        EMADynamicSampler,
+			KeyFields:      []string{"key1", "key2"},
+			GoalSampleRate: 10,
+		},
 	}
 }
 
+// createTracesProcessor creates a trace processor based on this config.
+func createTracesProcessor(
+	ctx context.Context,
+	set processor.Settings,
+	cfg component.Config,
+	nextConsumer consumer.Traces,
+) (processor.Traces, error) {
+	return newTracesProcessor(ctx, set, nextConsumer, cfg.(*Config))
+}
+
 // createLogsProcessor creates a log processor based on this config.
 func createLogsProcessor(
 	ctx context.Context,
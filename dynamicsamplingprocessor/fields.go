@@ -0,0 +1,191 @@
+package dynamicsamplingprocessor
+
+import (
+	"strings"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+// Pseudo-fields that can be used in key_fields to read log record and span fields instead of attributes. Only
+// trace_id and span_id are available for both; span_name, status_code and the trace fields are only available for
+// spans. The body_template field is resolved by the templateMiner of the logs processor.
+const (
+	traceIDField        = "trace_id"
+	spanIDField         = "span_id"
+	spanNameField       = "span_name"
+	statusCodeField     = "status_code"
+	traceHasErrorField  = "trace.has_error"
+	traceAnyPrefix      = "trace.any."
+	severityTextField   = "severity_text"
+	severityNumberField = "severity_number"
+	eventNameField      = "event_name"
+	bodyField           = "body"
+	bodyPathPrefix      = bodyField + "."
+	bodyTemplateField   = "body_template"
+)
+
+// fieldLookup returns the value of a key field, or false if the field is not present.
+type fieldLookup func(field string) (pcommon.Value, bool)
+
+// logFieldLookup returns a fieldLookup for the given log record. Pseudo-fields are read from the log record
+// itself. Any other field is looked up in the log record, scope and resource attributes, in that order, so
+// log record attributes take precedence over scope attributes and scope attributes take precedence over
+// resource attributes.
+func logFieldLookup(resource pcommon.Resource, scope pcommon.InstrumentationScope, lr plog.LogRecord) fieldLookup {
+	return (&logLookup{resource: resource, scope: scope, lr: lr}).field
+}
+
+// logLookup looks up the fields of a log record like logFieldLookup, and resolves the body_template pseudo-field
+// with templates when it is set. The logs processor reuses one logLookup for every record of a batch, pointing it
+// at each record in turn, so that looking up fields does not allocate for every record.
+type logLookup struct {
+	resource  pcommon.Resource
+	scope     pcommon.InstrumentationScope
+	lr        plog.LogRecord
+	templates *templateMiner
+}
+
+func (l *logLookup) field(field string) (pcommon.Value, bool) {
+	lr := l.lr
+	switch field {
+	case traceIDField:
+		return traceIDValue(lr.TraceID())
+	case spanIDField:
+		return spanIDValue(lr.SpanID())
+	case severityTextField:
+		if lr.SeverityText() == "" {
+			return pcommon.Value{}, false
+		}
+		return pcommon.NewValueStr(lr.SeverityText()), true
+	case severityNumberField:
+		if lr.SeverityNumber() == plog.SeverityNumberUnspecified {
+			return pcommon.Value{}, false
+		}
+		return pcommon.NewValueInt(int64(lr.SeverityNumber())), true
+	case eventNameField:
+		if lr.EventName() == "" {
+			return pcommon.Value{}, false
+		}
+		return pcommon.NewValueStr(lr.EventName()), true
+	case bodyField:
+		return lr.Body(), lr.Body().Type() != pcommon.ValueTypeEmpty
+	case bodyTemplateField:
+		if l.templates != nil {
+			return l.templates.bodyTemplate(lr)
+		}
+	}
+
+	if path, ok := strings.CutPrefix(field, bodyPathPrefix); ok && lr.Body().Type() == pcommon.ValueTypeMap {
+		return getMapPath(lr.Body().Map(), path)
+	}
+
+	return getAttribute(field, l.resource.Attributes(), l.scope.Attributes(), lr.Attributes())
+}
+
+// spanFieldLookup returns a fieldLookup for the given span. The trace_id, span_id, span_name and status_code
+// pseudo-fields are read from the span itself. Any other field is looked up in the span, scope and resource
+// attributes, in that order.
+func spanFieldLookup(resource pcommon.Resource, scope pcommon.InstrumentationScope, span ptrace.Span) fieldLookup {
+	return func(field string) (pcommon.Value, bool) {
+		switch field {
+		case traceIDField:
+			return traceIDValue(span.TraceID())
+		case spanIDField:
+			return spanIDValue(span.SpanID())
+		case spanNameField:
+			if span.Name() == "" {
+				return pcommon.Value{}, false
+			}
+			return pcommon.NewValueStr(span.Name()), true
+		case statusCodeField:
+			return pcommon.NewValueStr(span.Status().Code().String()), true
+		}
+		return getAttribute(field, resource.Attributes(), scope.Attributes(), span.Attributes())
+	}
+}
+
+// traceFieldLookup returns a fieldLookup for the spans of a trace. The trace.has_error field is true if any span
+// has an error status, and trace.any.<field> is the value of the field from the key span, or else from the first
+// other span that has it. Any other field is looked up in the key span.
+func traceFieldLookup(t *traceSpans) fieldLookup {
+	keySpan := t.keySpan()
+	keyLookup := spanFieldLookup(keySpan.resource, keySpan.scope, keySpan.span)
+	return func(field string) (pcommon.Value, bool) {
+		if field == traceHasErrorField {
+			for _, s := range t.spans {
+				if s.span.Status().Code() == ptrace.StatusCodeError {
+					return pcommon.NewValueBool(true), true
+				}
+			}
+			return pcommon.NewValueBool(false), true
+		}
+
+		if name, ok := strings.CutPrefix(field, traceAnyPrefix); ok {
+			if val, ok := keyLookup(name); ok {
+				return val, true
+			}
+			for i, s := range t.spans {
+				if i == t.key {
+					continue
+				}
+				if val, ok := spanFieldLookup(s.resource, s.scope, s.span)(name); ok {
+					return val, true
+				}
+			}
+			return pcommon.Value{}, false
+		}
+
+		return keyLookup(field)
+	}
+}
+
+// traceIDValue returns the hex encoded trace ID, or false if it is empty.
+func traceIDValue(id pcommon.TraceID) (pcommon.Value, bool) {
+	if id.IsEmpty() {
+		return pcommon.Value{}, false
+	}
+	return pcommon.NewValueStr(id.String()), true
+}
+
+// spanIDValue returns the hex encoded span ID, or false if it is empty.
+func spanIDValue(id pcommon.SpanID) (pcommon.Value, bool) {
+	if id.IsEmpty() {
+		return pcommon.Value{}, false
+	}
+	return pcommon.NewValueStr(id.String()), true
+}
+
+// getAttribute returns the named attribute from the record, scope or resource attributes. Record attributes take
+// precedence over scope attributes and scope attributes take precedence over resource attributes.
+func getAttribute(name string, resourceAttrs pcommon.Map, scopeAttrs pcommon.Map, recordAttrs pcommon.Map) (pcommon.Value, bool) {
+	if val, ok := recordAttrs.Get(name); ok {
+		return val, true
+	}
+	if val, ok := scopeAttrs.Get(name); ok {
+		return val, true
+	}
+	return resourceAttrs.Get(name)
+}
+
+// getMapPath returns the value at a dot separated path in a map. A key that itself contains dots is matched
+// before the path is split, so both {"http.method": "GET"} and {"http": {"method": "GET"}} resolve for the
+// path "http.method".
+func getMapPath(m pcommon.Map, path string) (pcommon.Value, bool) {
+	if val, ok := m.Get(path); ok {
+		return val, true
+	}
+
+	for i := 0; i < len(path); i++ {
+		if path[i] != '.' {
+			continue
+		}
+		if val, ok := m.Get(path[:i]); ok && val.Type() == pcommon.ValueTypeMap {
+			if val, ok := getMapPath(val.Map(), path[i+1:]); ok {
+				return val, true
+			}
+		}
+	}
+	return pcommon.Value{}, false
+}
@@ -0,0 +1,135 @@
+package dynamicsamplingprocessor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+func TestLogFieldLookup(t *testing.T) {
+	resource := pcommon.NewResource()
+	resource.Attributes().PutStr("service.name", "checkout")
+	resource.Attributes().PutStr("shared", "resource")
+
+	scope := pcommon.NewInstrumentationScope()
+	scope.Attributes().PutStr("scope.only", "scope")
+	scope.Attributes().PutStr("shared", "scope")
+
+	lr := plog.NewLogRecord()
+	lr.Attributes().PutStr("shared", "record")
+	lr.SetSeverityText("ERROR")
+	lr.SetSeverityNumber(plog.SeverityNumberError)
+	lr.SetEventName("payment.failed")
+	lr.SetTraceID(pcommon.TraceID([16]byte{0x0a, 0x0b}))
+	lr.SetSpanID(pcommon.SpanID([8]byte{0x0c}))
+	body := lr.Body().SetEmptyMap()
+	body.PutStr("http.method", "GET")
+	body.PutEmptyMap("http").PutInt("status_code", 500)
+
+	lookup := logFieldLookup(resource, scope, lr)
+
+	tests := []struct {
+		field    string
+		expected string
+		found    bool
+	}{
+		{field: "shared", expected: "record", found: true},
+		{field: "scope.only", expected: "scope", found: true},
+		{field: "service.name", expected: "checkout", found: true},
+		{field: "missing", found: false},
+		{field: "severity_text", expected: "ERROR", found: true},
+		{field: "severity_number", expected: "17", found: true},
+		{field: "event_name", expected: "payment.failed", found: true},
+		{field: "trace_id", expected: "0a0b0000000000000000000000000000", found: true},
+		{field: "span_id", expected: "0c00000000000000", found: true},
+		{field: "body.http.method", expected: "GET", found: true},
+		{field: "body.http.status_code", expected: "500", found: true},
+		{field: "body.http.missing", found: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.field, func(t *testing.T) {
+			val, ok := lookup(tt.field)
+			assert.Equal(t, tt.found, ok)
+			if tt.found {
+				assert.Equal(t, tt.expected, val.AsString())
+			}
+		})
+	}
+}
+
+func TestLogFieldLookupUnsetPseudoFields(t *testing.T) {
+	lr := plog.NewLogRecord()
+	lookup := logFieldLookup(pcommon.NewResource(), pcommon.NewInstrumentationScope(), lr)
+
+	for _, field := range []string{"trace_id", "span_id", "severity_text", "severity_number", "event_name", "body", "body.key"} {
+		_, ok := lookup(field)
+		assert.False(t, ok, field)
+	}
+
+	lr.Body().SetStr("connection reset")
+	val, ok := lookup("body")
+	assert.True(t, ok)
+	assert.Equal(t, "connection reset", val.Str())
+}
+
+func TestMakeDynsampleKeyFromResource(t *testing.T) {
+	resource := pcommon.NewResource()
+	resource.Attributes().PutStr("k8s.namespace.name", "payments")
+
+	lr := plog.NewLogRecord()
+	lr.SetSeverityText("WARN")
+
+	key := makeDynsampleKey([]string{"k8s.namespace.name", "severity_text"}, logFieldLookup(resource, pcommon.NewInstrumentationScope(), lr))
+	assert.Equal(t, "payments_WARN", key)
+}
+
+func TestTraceFieldLookup(t *testing.T) {
+	resource := pcommon.NewResource()
+	resource.Attributes().PutStr("service.name", "checkout")
+	scope := pcommon.NewInstrumentationScope()
+
+	var trace traceSpans
+	for i, name := range []string{"SELECT", "GET /cart", "charge"} {
+		span := ptrace.NewSpan()
+		span.SetTraceID(pcommon.TraceID([16]byte{1}))
+		span.SetSpanID(pcommon.SpanID([8]byte{byte(i + 1)}))
+		span.SetName(name)
+		if name != "GET /cart" {
+			span.SetParentSpanID(pcommon.SpanID([8]byte{2}))
+		}
+		trace.add(spanContext{span: span, scope: scope, resource: resource})
+	}
+	trace.spans[0].span.Attributes().PutStr("db.system", "postgresql")
+	trace.spans[2].span.Status().SetCode(ptrace.StatusCodeError)
+
+	lookup := traceFieldLookup(&trace)
+
+	tests := []struct {
+		field    string
+		expected string
+		found    bool
+	}{
+		{field: "span_name", expected: "GET /cart", found: true},
+		{field: "status_code", expected: "Unset", found: true},
+		{field: "service.name", expected: "checkout", found: true},
+		{field: "db.system", found: false},
+		{field: "trace.has_error", expected: "true", found: true},
+		{field: "trace.any.db.system", expected: "postgresql", found: true},
+		{field: "trace.any.span_name", expected: "GET /cart", found: true},
+		{field: "trace.any.missing", found: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.field, func(t *testing.T) {
+			val, ok := lookup(tt.field)
+			assert.Equal(t, tt.found, ok)
+			if tt.found {
+				assert.Equal(t, tt.expected, val.AsString())
+			}
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package dynamicsamplingprocessor
+
+import (
+	"fmt"
+
+	"go.opentelemetry.io/collector/component"
+)
+
+// Goals are the goals of a sampler. Only the goal used by the sampler is applied: GoalSampleRate for
+// EMADynamicSampler, AvgSampleRateSampler and AvgSampleWithMinSampler, and GoalThroughputPerSecond for
+// EMAThroughputSampler, TotalThroughputSampler, PerKeyThroughputSampler and WindowedThroughputSampler. Only
+// WindowedThroughputSampler takes a fractional throughput goal; the others round it down, to no less than 1.
+type Goals struct {
+	GoalSampleRate          int
+	GoalThroughputPerSecond float64
+}
+
+// GoalSource is implemented by extensions that change the goals of the dynamic sampler at runtime, for example
+// from OpAMP remote configuration. Changing goals this way keeps the rates the samplers have learned for each key,
+// which reloading the collector with a new configuration does not.
+type GoalSource interface {
+	// SubscribeGoals registers update to be called with new goals for the processor with the given ID, keyed by
+	// sampler name. The sampler configured at the top level is named "default". Samplers not in goals keep their
+	// goals. update returns an error, and changes no goal, if the goals name an unknown sampler or are invalid for
+	// their sampler. The returned function removes the subscription.
+	SubscribeGoals(id component.ID, update func(goals map[string]Goals) error) (unsubscribe func())
+}
+
+// subscribeGoals subscribes the samplers of d to the GoalSource extension with the given ID. It returns a no-op
+// unsubscribe function when no goal source is configured.
+func subscribeGoals[K any](host component.Host, goalSourceID *component.ID, componentID component.ID, d *decider[K]) (func(), error) {
+	if goalSourceID == nil {
+		return func() {}, nil
+	}
+
+	ext, ok := host.GetExtensions()[*goalSourceID]
+	if !ok {
+		return nil, fmt.Errorf("goal source extension %q not found", goalSourceID)
+	}
+	source, ok := ext.(GoalSource)
+	if !ok {
+		return nil, fmt.Errorf("extension %q does not implement GoalSource", goalSourceID)
+	}
+	return source.SubscribeGoals(componentID, d.setGoals), nil
+}
@@ -0,0 +1,208 @@
+package dynamicsamplingprocessor
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/collector/consumer/consumertest"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/processor/processortest"
+
+	dynsampler "github.com/honeycombio/dynsampler-go"
+)
+
+// testGoalSource is a GoalSource that remembers the subscriptions made to it.
+type testGoalSource struct {
+	component.StartFunc
+	component.ShutdownFunc
+
+	updates map[component.ID]func(map[string]Goals) error
+}
+
+func (s *testGoalSource) SubscribeGoals(id component.ID, update func(map[string]Goals) error) func() {
+	s.updates[id] = update
+	return func() { delete(s.updates, id) }
+}
+
+func TestDeciderSetGoalsKeepsState(t *testing.T) {
+	cfg := createDefaultConfig().(*Config)
+	cfg.KeyFields = []string{"key1"}
+	cfg.GoalSampleRate = 10
+	d := newTestLogDecider(t, cfg)
+
+	before := d.sampler.getSampler()
+	require.NoError(t, before.LoadState([]byte(`{"saved_sample_rates":{"checkout":7},"moving_average":{"checkout":70}}`)))
+
+	require.NoError(t, d.setGoals(map[string]Goals{"default": {GoalSampleRate: 20}}))
+
+	after := d.sampler.getSampler()
+	assert.NotSame(t, before, after)
+	require.IsType(t, &dynsampler.EMASampleRate{}, after)
+	assert.Equal(t, 20, after.(*dynsampler.EMASampleRate).GoalSampleRate)
+	assert.Equal(t, 7, after.GetSampleRate("checkout"))
+}
+
+func TestDeciderSetGoalsConcurrent(t *testing.T) {
+	cfg := createDefaultConfig().(*Config)
+	cfg.KeyFields = []string{"key1"}
+	cfg.GoalSampleRate = 10
+	d := newTestLogDecider(t, cfg)
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+					assert.Positive(t, decideLog(d, func(lr plog.LogRecord) { lr.Attributes().PutStr("key1", "checkout") }).sampleRate)
+				}
+			}
+		}()
+	}
+	for i := range 50 {
+		require.NoError(t, d.setGoals(map[string]Goals{"default": {GoalSampleRate: 10 + i}}))
+	}
+	close(done)
+	wg.Wait()
+
+	assert.Equal(t, 59, d.sampler.getSampler().(*dynsampler.EMASampleRate).GoalSampleRate)
+	assert.Equal(t, 59, d.sampler.config().GoalSampleRate)
+}
+
+func TestDeciderSetGoalsErrors(t *testing.T) {
+	cfg := createDefaultConfig().(*Config)
+	cfg.KeyFields = []string{"key1"}
+	cfg.GoalSampleRate = 10
+	cfg.Samplers = map[string]SamplerConfig{
+		"audit": {Sampler: StaticSampler, KeyFields: []string{"key1"}, Static: StaticConfig{Default: 5}},
+	}
+	d := newTestLogDecider(t, cfg)
+	before := d.sampler.getSampler()
+
+	tests := []struct {
+		name     string
+		goals    map[string]Goals
+		expected string
+	}{
+		{
+			name:     "unknown sampler",
+			goals:    map[string]Goals{"default": {GoalSampleRate: 20}, "missing": {GoalSampleRate: 20}},
+			expected: `unknown sampler "missing"`,
+		},
+		{
+			name:     "sampler without a goal",
+			goals:    map[string]Goals{"default": {GoalSampleRate: 20}, "audit": {GoalSampleRate: 20}},
+			expected: `sampler "audit": StaticSampler has no goal to change`,
+		},
+		{
+			name:     "missing goal",
+			goals:    map[string]Goals{"default": {GoalThroughputPerSecond: 20}},
+			expected: `sampler "default": EMADynamicSampler goal_sample_rate must be set and greater than 0`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.EqualError(t, d.setGoals(tt.goals), tt.expected)
+			assert.Same(t, before, d.sampler.getSampler())
+		})
+	}
+}
+
+func TestPartitionedSamplerSetConfig(t *testing.T) {
+	cfg := SamplerConfig{
+		Sampler:        EMADynamicSampler,
+		KeyFields:      []string{"key1"},
+		GoalSampleRate: 10,
+		PartitionBy:    "service.name",
+		Partitions: map[string]PartitionConfig{
+			"checkout": {GoalSampleRate: 50},
+		},
+	}
+	p := newPartitionedSampler(&cfg)
+	t.Cleanup(func() { require.NoError(t, p.stop()) })
+	now := time.Now()
+
+	checkout, err := p.get("checkout", now)
+	require.NoError(t, err)
+	payments, err := p.get("payments", now)
+	require.NoError(t, err)
+
+	require.NoError(t, p.setConfig(cfg.withGoal(20, 0)))
+
+	again, err := p.get("checkout", now)
+	require.NoError(t, err)
+	assert.Same(t, checkout, again)
+
+	again, err = p.get("payments", now)
+	require.NoError(t, err)
+	assert.NotSame(t, payments, again)
+	assert.Equal(t, 20, again.(*dynsampler.EMASampleRate).GoalSampleRate)
+
+	created, err := p.get("search", now)
+	require.NoError(t, err)
+	assert.Equal(t, 20, created.(*dynsampler.EMASampleRate).GoalSampleRate)
+}
+
+func TestProcessorSubscribesToGoalSource(t *testing.T) {
+	sourceID := component.MustNewID("remote_goals")
+	source := &testGoalSource{updates: map[component.ID]func(map[string]Goals) error{}}
+	host := storageHost{Host: componenttest.NewNopHost(), extensions: map[component.ID]component.Component{sourceID: source}}
+
+	cfg := createDefaultConfig().(*Config)
+	cfg.KeyFields = []string{"key1"}
+	cfg.GoalSourceID = &sourceID
+
+	set := processortest.NewNopSettings(typ)
+	tp, err := NewFactory().CreateTraces(context.Background(), set, cfg, consumertest.NewNop())
+	require.NoError(t, err)
+	require.NoError(t, tp.Start(context.Background(), host))
+
+	require.Contains(t, source.updates, set.ID)
+	assert.NoError(t, source.updates[set.ID](map[string]Goals{"default": {GoalSampleRate: 20}}))
+
+	require.NoError(t, tp.Shutdown(context.Background()))
+	assert.Empty(t, source.updates)
+}
+
+func TestProcessorGoalSourceErrors(t *testing.T) {
+	sourceID := component.MustNewID("remote_goals")
+	cfg := createDefaultConfig().(*Config)
+	cfg.KeyFields = []string{"key1"}
+	cfg.GoalSourceID = &sourceID
+
+	tests := []struct {
+		name       string
+		extensions map[component.ID]component.Component
+		expected   string
+	}{
+		{
+			name:     "missing extension",
+			expected: `goal source extension "remote_goals" not found`,
+		},
+		{
+			name:       "extension without GoalSource",
+			extensions: map[component.ID]component.Component{sourceID: &memoryStorage{}},
+			expected:   `extension "remote_goals" does not implement GoalSource`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lp, err := NewFactory().CreateLogs(context.Background(), processortest.NewNopSettings(typ), cfg, consumertest.NewNop())
+			require.NoError(t, err)
+			host := storageHost{Host: componenttest.NewNopHost(), extensions: tt.extensions}
+			assert.EqualError(t, lp.Start(context.Background(), host), tt.expected)
+			require.NoError(t, lp.Shutdown(context.Background()))
+		})
+	}
+}
@@ -3,14 +3,15 @@
 package metadata
 
 import (
+	"context"
 	"errors"
+	"sync"
 
 	"go.opentelemetry.io/otel/metric"
-	"go.opentelemetry.io/otel/metric/noop"
+	"go.opentelemetry.io/otel/metric/embedded"
 	"go.opentelemetry.io/otel/trace"
 
 	"go.opentelemetry.io/collector/component"
-	"go.opentelemetry.io/collector/config/configtelemetry"
 )
 
 func Meter(settings component.TelemetrySettings) metric.Meter {
@@ -24,39 +25,195 @@ func Tracer(settings component.TelemetrySettings) trace.Tracer {
 // TelemetryBuilder provides an interface for components to report telemetry
 // as defined in metadata and user config.
 type TelemetryBuilder struct {
-	meter                                   metric.Meter
-	ProcessorDynamicSamplerCountLogsSampled metric.Int64Counter
-	level                                   configtelemetry.Level
+	meter                                     metric.Meter
+	mu                                        sync.Mutex
+	registrations                             []metric.Registration
+	ProcessorDynamicSamplerActiveKeys         metric.Int64ObservableGauge
+	ProcessorDynamicSamplerCountLogsSampled   metric.Int64Counter
+	ProcessorDynamicSamplerCountSpansSampled  metric.Int64Counter
+	ProcessorDynamicSamplerKeySampleRate      metric.Int64ObservableGauge
+	ProcessorDynamicSamplerOverflowRecords    metric.Int64ObservableCounter
+	ProcessorDynamicSamplerSampleRate         metric.Int64Histogram
+	ProcessorDynamicSamplerTailBufferedSpans  metric.Int64ObservableGauge
+	ProcessorDynamicSamplerTailBufferedTraces metric.Int64ObservableGauge
+	ProcessorDynamicSamplerTailEvictedTraces  metric.Int64Counter
+	ProcessorDynamicSamplerTailLateSpans      metric.Int64Counter
 }
 
-// telemetryBuilderOption applies changes to default builder.
-type telemetryBuilderOption func(*TelemetryBuilder)
+// TelemetryBuilderOption applies changes to default builder.
+type TelemetryBuilderOption interface {
+	apply(*TelemetryBuilder)
+}
+
+type telemetryBuilderOptionFunc func(mb *TelemetryBuilder)
+
+func (tbof telemetryBuilderOptionFunc) apply(mb *TelemetryBuilder) {
+	tbof(mb)
+}
+
+// RegisterProcessorDynamicSamplerActiveKeysCallback sets callback for observable ProcessorDynamicSamplerActiveKeys metric.
+func (builder *TelemetryBuilder) RegisterProcessorDynamicSamplerActiveKeysCallback(cb metric.Int64Callback) error {
+	reg, err := builder.meter.RegisterCallback(func(ctx context.Context, o metric.Observer) error {
+		cb(ctx, &observerInt64{inst: builder.ProcessorDynamicSamplerActiveKeys, obs: o})
+		return nil
+	}, builder.ProcessorDynamicSamplerActiveKeys)
+	if err != nil {
+		return err
+	}
+	builder.mu.Lock()
+	defer builder.mu.Unlock()
+	builder.registrations = append(builder.registrations, reg)
+	return nil
+}
+
+// RegisterProcessorDynamicSamplerKeySampleRateCallback sets callback for observable ProcessorDynamicSamplerKeySampleRate metric.
+func (builder *TelemetryBuilder) RegisterProcessorDynamicSamplerKeySampleRateCallback(cb metric.Int64Callback) error {
+	reg, err := builder.meter.RegisterCallback(func(ctx context.Context, o metric.Observer) error {
+		cb(ctx, &observerInt64{inst: builder.ProcessorDynamicSamplerKeySampleRate, obs: o})
+		return nil
+	}, builder.ProcessorDynamicSamplerKeySampleRate)
+	if err != nil {
+		return err
+	}
+	builder.mu.Lock()
+	defer builder.mu.Unlock()
+	builder.registrations = append(builder.registrations, reg)
+	return nil
+}
+
+// RegisterProcessorDynamicSamplerOverflowRecordsCallback sets callback for observable ProcessorDynamicSamplerOverflowRecords metric.
+func (builder *TelemetryBuilder) RegisterProcessorDynamicSamplerOverflowRecordsCallback(cb metric.Int64Callback) error {
+	reg, err := builder.meter.RegisterCallback(func(ctx context.Context, o metric.Observer) error {
+		cb(ctx, &observerInt64{inst: builder.ProcessorDynamicSamplerOverflowRecords, obs: o})
+		return nil
+	}, builder.ProcessorDynamicSamplerOverflowRecords)
+	if err != nil {
+		return err
+	}
+	builder.mu.Lock()
+	defer builder.mu.Unlock()
+	builder.registrations = append(builder.registrations, reg)
+	return nil
+}
+
+// RegisterProcessorDynamicSamplerTailBufferedSpansCallback sets callback for observable ProcessorDynamicSamplerTailBufferedSpans metric.
+func (builder *TelemetryBuilder) RegisterProcessorDynamicSamplerTailBufferedSpansCallback(cb metric.Int64Callback) error {
+	reg, err := builder.meter.RegisterCallback(func(ctx context.Context, o metric.Observer) error {
+		cb(ctx, &observerInt64{inst: builder.ProcessorDynamicSamplerTailBufferedSpans, obs: o})
+		return nil
+	}, builder.ProcessorDynamicSamplerTailBufferedSpans)
+	if err != nil {
+		return err
+	}
+	builder.mu.Lock()
+	defer builder.mu.Unlock()
+	builder.registrations = append(builder.registrations, reg)
+	return nil
+}
+
+// RegisterProcessorDynamicSamplerTailBufferedTracesCallback sets callback for observable ProcessorDynamicSamplerTailBufferedTraces metric.
+func (builder *TelemetryBuilder) RegisterProcessorDynamicSamplerTailBufferedTracesCallback(cb metric.Int64Callback) error {
+	reg, err := builder.meter.RegisterCallback(func(ctx context.Context, o metric.Observer) error {
+		cb(ctx, &observerInt64{inst: builder.ProcessorDynamicSamplerTailBufferedTraces, obs: o})
+		return nil
+	}, builder.ProcessorDynamicSamplerTailBufferedTraces)
+	if err != nil {
+		return err
+	}
+	builder.mu.Lock()
+	defer builder.mu.Unlock()
+	builder.registrations = append(builder.registrations, reg)
+	return nil
+}
+
+type observerInt64 struct {
+	embedded.Int64Observer
+	inst metric.Int64Observable
+	obs  metric.Observer
+}
+
+func (oi *observerInt64) Observe(value int64, opts ...metric.ObserveOption) {
+	oi.obs.ObserveInt64(oi.inst, value, opts...)
+}
 
-// WithLevel sets the current telemetry level for the component.
-func WithLevel(lvl configtelemetry.Level) telemetryBuilderOption {
-	return func(builder *TelemetryBuilder) {
-		builder.level = lvl
+// Shutdown unregister all registered callbacks for async instruments.
+func (builder *TelemetryBuilder) Shutdown() {
+	builder.mu.Lock()
+	defer builder.mu.Unlock()
+	for _, reg := range builder.registrations {
+		reg.Unregister()
 	}
 }
 
 // NewTelemetryBuilder provides a struct with methods to update all internal telemetry
 // for a component
-func NewTelemetryBuilder(settings component.TelemetrySettings, options ...telemetryBuilderOption) (*TelemetryBuilder, error) {
-	builder := TelemetryBuilder{level: configtelemetry.LevelBasic}
+func NewTelemetryBuilder(settings component.TelemetrySettings, options ...TelemetryBuilderOption) (*TelemetryBuilder, error) {
+	builder := TelemetryBuilder{}
 	for _, op := range options {
-		op(&builder)
+		op.apply(&builder)
 	}
+	builder.meter = Meter(settings)
 	var err, errs error
-	if builder.level >= configtelemetry.LevelBasic {
-		builder.meter = Meter(settings)
-	} else {
-		builder.meter = noop.Meter{}
-	}
+	builder.ProcessorDynamicSamplerActiveKeys, err = builder.meter.Int64ObservableGauge(
+		"otelcol_processor_dynamic_sampler_active_keys",
+		metric.WithDescription("Number of keys tracked by each sampler"),
+		metric.WithUnit("{keys}"),
+	)
+	errs = errors.Join(errs, err)
 	builder.ProcessorDynamicSamplerCountLogsSampled, err = builder.meter.Int64Counter(
 		"otelcol_processor_dynamic_sampler_count_logs_sampled",
 		metric.WithDescription("Count of logs that were sampled or not"),
 		metric.WithUnit("1"),
 	)
 	errs = errors.Join(errs, err)
+	builder.ProcessorDynamicSamplerCountSpansSampled, err = builder.meter.Int64Counter(
+		"otelcol_processor_dynamic_sampler_count_spans_sampled",
+		metric.WithDescription("Count of spans that were sampled or not"),
+		metric.WithUnit("1"),
+	)
+	errs = errors.Join(errs, err)
+	builder.ProcessorDynamicSamplerKeySampleRate, err = builder.meter.Int64ObservableGauge(
+		"otelcol_processor_dynamic_sampler_key_sample_rate",
+		metric.WithDescription("Current sample rate of the busiest keys of each sampler"),
+		metric.WithUnit("1"),
+	)
+	errs = errors.Join(errs, err)
+	builder.ProcessorDynamicSamplerOverflowRecords, err = builder.meter.Int64ObservableCounter(
+		"otelcol_processor_dynamic_sampler_overflow_records",
+		metric.WithDescription("Count of records sampled with the overflow key because their sampler reached its key limit"),
+		metric.WithUnit("1"),
+	)
+	errs = errors.Join(errs, err)
+	builder.ProcessorDynamicSamplerSampleRate, err = builder.meter.Int64Histogram(
+		"otelcol_processor_dynamic_sampler_sample_rate",
+		metric.WithDescription("Sample rates applied to logs and traces"),
+		metric.WithUnit("1"),
+		metric.WithExplicitBucketBoundaries([]float64{1, 2, 5, 10, 25, 50, 100, 250, 500, 1000, 10000}...),
+	)
+	errs = errors.Join(errs, err)
+	builder.ProcessorDynamicSamplerTailBufferedSpans, err = builder.meter.Int64ObservableGauge(
+		"otelcol_processor_dynamic_sampler_tail_buffered_spans",
+		metric.WithDescription("Number of spans buffered for tail sampling"),
+		metric.WithUnit("{spans}"),
+	)
+	errs = errors.Join(errs, err)
+	builder.ProcessorDynamicSamplerTailBufferedTraces, err = builder.meter.Int64ObservableGauge(
+		"otelcol_processor_dynamic_sampler_tail_buffered_traces",
+		metric.WithDescription("Number of traces buffered for tail sampling"),
+		metric.WithUnit("{traces}"),
+	)
+	errs = errors.Join(errs, err)
+	builder.ProcessorDynamicSamplerTailEvictedTraces, err = builder.meter.Int64Counter(
+		"otelcol_processor_dynamic_sampler_tail_evicted_traces",
+		metric.WithDescription("Count of traces decided before their decision wait was over because the tail sampling buffer was full"),
+		metric.WithUnit("{traces}"),
+	)
+	errs = errors.Join(errs, err)
+	builder.ProcessorDynamicSamplerTailLateSpans, err = builder.meter.Int64Counter(
+		"otelcol_processor_dynamic_sampler_tail_late_spans",
+		metric.WithDescription("Count of spans that arrived after the decision for their trace was made, by whether they were sampled"),
+		metric.WithUnit("{spans}"),
+	)
+	errs = errors.Join(errs, err)
 	return &builder, errs
 }
@@ -0,0 +1,166 @@
+package dynamicsamplingprocessor
+
+import (
+	"bytes"
+	"strconv"
+	"sync"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+)
+
+const (
+	// keySeparator separates the parts of a sampler key.
+	keySeparator = '_'
+
+	// keyEscape is written before a keySeparator or keyEscape that is part of a value, so that no value can be
+	// mistaken for the boundary between two parts.
+	keyEscape = '\\'
+
+	// missingKeyPart is written in place of a missing value. No escaped value reads as missingKeyPart, so a missing
+	// field gets a different key than a field set to the empty string.
+	missingKeyPart = `\-`
+)
+
+// keyBuilders holds keyBuilders for reuse, so that building a key allocates nothing but the key itself.
+var keyBuilders = sync.Pool{
+	New: func() any {
+		return &keyBuilder{buf: make([]byte, 0, 64)}
+	},
+}
+
+// keyBuilder builds a sampler key from the values of the key fields followed by the results of the key expressions.
+// Every field and expression keeps its position in the key, so {a: x, b: y} and {a: y, b: x} get different keys.
+// Separators and escapes within values are escaped, so {a: x_y, b: z} and {a: x, b: y_z} get different keys too. A
+// missing field, or a field or expression with a value that cannot be used in a key, is written as missingKeyPart.
+type keyBuilder struct {
+	buf   []byte
+	parts int
+
+	// scratch holds a value while it is escaped.
+	scratch []byte
+}
+
+// getKeyBuilder returns an empty keyBuilder. It must be returned with release once the key has been built.
+func getKeyBuilder() *keyBuilder {
+	b := keyBuilders.Get().(*keyBuilder)
+	b.buf = b.buf[:0]
+	b.parts = 0
+	return b
+}
+
+// release returns the keyBuilder to the pool.
+func (b *keyBuilder) release() {
+	keyBuilders.Put(b)
+}
+
+// next starts the next part of the key.
+func (b *keyBuilder) next() {
+	if b.parts > 0 {
+		b.buf = append(b.buf, keySeparator)
+	}
+	b.parts++
+}
+
+// addFields adds the value of each of the fields, as returned by lookup.
+func (b *keyBuilder) addFields(fields []string, lookup fieldLookup) {
+	for _, field := range fields {
+		b.next()
+		start := len(b.buf)
+		var ok bool
+		if val, found := lookup(field); found {
+			b.buf, ok = appendKeyPart(b.buf, val)
+		}
+		b.finish(start, ok)
+	}
+}
+
+// addValue adds the result of a key expression.
+func (b *keyBuilder) addValue(val any) {
+	b.next()
+	start := len(b.buf)
+	var ok bool
+	b.buf, ok = appendKeyPartFromAny(b.buf, val)
+	b.finish(start, ok)
+}
+
+// addMissing adds a missing part, for a key expression that failed to evaluate.
+func (b *keyBuilder) addMissing() {
+	b.next()
+	b.buf = append(b.buf, missingKeyPart...)
+}
+
+// finish escapes the value written to the key from start, or writes missingKeyPart if there is no value.
+func (b *keyBuilder) finish(start int, ok bool) {
+	if !ok {
+		b.buf = append(b.buf, missingKeyPart...)
+		return
+	}
+	if bytes.IndexAny(b.buf[start:], string(keySeparator)+string(keyEscape)) < 0 {
+		return
+	}
+	b.scratch = append(b.scratch[:0], b.buf[start:]...)
+	b.buf = b.buf[:start]
+	for _, c := range b.scratch {
+		if c == keySeparator || c == keyEscape {
+			b.buf = append(b.buf, keyEscape)
+		}
+		b.buf = append(b.buf, c)
+	}
+}
+
+// String returns the key.
+func (b *keyBuilder) String() string {
+	return string(b.buf)
+}
+
+// makeDynsampleKey builds the sampler key from the values of the key fields.
+func makeDynsampleKey(keyFields []string, lookup fieldLookup) string {
+	b := getKeyBuilder()
+	defer b.release()
+	b.addFields(keyFields, lookup)
+	return b.String()
+}
+
+// keyPart returns the string form of a value for use in a sampler key. Only scalar values can be used.
+func keyPart(val pcommon.Value) (string, bool) {
+	if val.Type() == pcommon.ValueTypeStr {
+		return val.Str(), true
+	}
+	b, ok := appendKeyPart(nil, val)
+	return string(b), ok
+}
+
+// appendKeyPart appends the string form of a value for use in a sampler key to buf. Only scalar values can be
+// used, and buf is returned unchanged for any other value.
+func appendKeyPart(buf []byte, val pcommon.Value) ([]byte, bool) {
+	switch val.Type() {
+	case pcommon.ValueTypeBool:
+		return strconv.AppendBool(buf, val.Bool()), true
+	case pcommon.ValueTypeInt:
+		return strconv.AppendInt(buf, val.Int(), 10), true
+	case pcommon.ValueTypeDouble:
+		return strconv.AppendFloat(buf, val.Double(), 'E', -1, 64), true
+	case pcommon.ValueTypeStr:
+		return append(buf, val.Str()...), true
+	default:
+		return buf, false
+	}
+}
+
+// appendKeyPartFromAny appends the string form of an OTTL expression result for use in a sampler key to buf.
+func appendKeyPartFromAny(buf []byte, val any) ([]byte, bool) {
+	switch v := val.(type) {
+	case string:
+		return append(buf, v...), true
+	case bool:
+		return strconv.AppendBool(buf, v), true
+	case int64:
+		return strconv.AppendInt(buf, v, 10), true
+	case float64:
+		return strconv.AppendFloat(buf, v, 'E', -1, 64), true
+	case pcommon.Value:
+		return appendKeyPart(buf, v)
+	default:
+		return buf, false
+	}
+}
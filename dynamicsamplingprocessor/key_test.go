@@ -0,0 +1,107 @@
+package dynamicsamplingprocessor
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+)
+
+func TestMakeDynsampleKeyPositions(t *testing.T) {
+	lookup := func(attrs map[string]any) fieldLookup {
+		lr := plog.NewLogRecord()
+		_ = lr.Attributes().FromRaw(attrs)
+		return logFieldLookup(pcommon.NewResource(), pcommon.NewInstrumentationScope(), lr)
+	}
+	fields := []string{"a", "b", "c"}
+
+	tests := []struct {
+		name     string
+		attrs    map[string]any
+		expected string
+	}{
+		{name: "all fields", attrs: map[string]any{"a": "x", "b": "y", "c": "z"}, expected: "x_y_z"},
+		{name: "swapped values", attrs: map[string]any{"a": "y", "b": "x", "c": "z"}, expected: "y_x_z"},
+		{name: "missing first field", attrs: map[string]any{"b": "y", "c": "z"}, expected: `\-_y_z`},
+		{name: "missing middle field", attrs: map[string]any{"a": "x", "c": "z"}, expected: `x_\-_z`},
+		{name: "missing last field", attrs: map[string]any{"a": "x", "b": "y"}, expected: `x_y_\-`},
+		{name: "no fields", attrs: map[string]any{}, expected: `\-_\-_\-`},
+		{name: "empty values", attrs: map[string]any{"a": "", "b": "", "c": ""}, expected: "__"},
+		{name: "scalar types", attrs: map[string]any{"a": true, "b": 42, "c": 1.5}, expected: "true_42_1.5E+00"},
+		{name: "non-scalar value", attrs: map[string]any{"a": "x", "b": map[string]any{"k": "v"}, "c": "z"}, expected: `x_\-_z`},
+		{name: "escaped separator", attrs: map[string]any{"a": "x_y", "b": "z", "c": `w\`}, expected: `x\_y_z_w\\`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, makeDynsampleKey(fields, lookup(tt.attrs)))
+		})
+	}
+}
+
+func TestMakeDynsampleKeyDistinct(t *testing.T) {
+	lookup := func(attrs map[string]any) fieldLookup {
+		lr := plog.NewLogRecord()
+		_ = lr.Attributes().FromRaw(attrs)
+		return logFieldLookup(pcommon.NewResource(), pcommon.NewInstrumentationScope(), lr)
+	}
+	fields := []string{"a", "b"}
+
+	tests := []struct {
+		name   string
+		first  map[string]any
+		second map[string]any
+	}{
+		{name: "separator in values", first: map[string]any{"a": "x_y", "b": "z"}, second: map[string]any{"a": "x", "b": "y_z"}},
+		{name: "escape in values", first: map[string]any{"a": `x\`, "b": "y"}, second: map[string]any{"a": "x", "b": `\y`}},
+		{name: "missing and empty", first: map[string]any{"a": "x"}, second: map[string]any{"a": "x", "b": ""}},
+		{name: "missing and marker", first: map[string]any{"a": "x"}, second: map[string]any{"a": "x", "b": missingKeyPart}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.NotEqual(t, makeDynsampleKey(fields, lookup(tt.first)), makeDynsampleKey(fields, lookup(tt.second)))
+		})
+	}
+}
+
+func TestKeyBuilderReuse(t *testing.T) {
+	lr := plog.NewLogRecord()
+	lr.Attributes().PutStr("a", "a-much-longer-value-than-the-next-one")
+	lookup := logFieldLookup(pcommon.NewResource(), pcommon.NewInstrumentationScope(), lr)
+	first := makeDynsampleKey([]string{"a"}, lookup)
+
+	lr.Attributes().PutStr("a", "short")
+	assert.Equal(t, "short", makeDynsampleKey([]string{"a"}, lookup))
+	assert.Equal(t, "a-much-longer-value-than-the-next-one", first)
+}
+
+func BenchmarkMakeDynsampleKey(b *testing.B) {
+	resource := pcommon.NewResource()
+	resource.Attributes().PutStr("service.name", "checkout")
+	lr := plog.NewLogRecord()
+	lr.SetSeverityText("WARN")
+	lr.Attributes().PutInt("http.response.status_code", 503)
+	lr.Attributes().PutStr("http.route", "/cart/{id}")
+	lookup := logFieldLookup(resource, pcommon.NewInstrumentationScope(), lr)
+	fields := []string{"service.name", "http.response.status_code", "http.route"}
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_ = makeDynsampleKey(fields, lookup)
+	}
+}
+
+// benchmarkLookups returns lookups for records spread over the given number of keys.
+func benchmarkLookups(keys int) []fieldLookup {
+	lookups := make([]fieldLookup, keys)
+	for i := range lookups {
+		lr := plog.NewLogRecord()
+		lr.Attributes().PutStr("service.name", "service-"+strconv.Itoa(i%50))
+		lr.Attributes().PutInt("http.response.status_code", int64(200+i/50))
+		lookups[i] = logFieldLookup(pcommon.NewResource(), pcommon.NewInstrumentationScope(), lr)
+	}
+	return lookups
+}
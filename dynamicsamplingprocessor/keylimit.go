@@ -0,0 +1,87 @@
+package dynamicsamplingprocessor
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// overflowKey is the key shared by records whose keys were first seen after the key limit was reached.
+	overflowKey = "__overflow__"
+
+	// defaultKeyLimitInterval is used when key_limit_interval is not set.
+	defaultKeyLimitInterval = time.Minute
+)
+
+// keyLimiter caps the number of distinct keys a sampler sees per interval. Keys that were seen before the cap was
+// reached keep their own key for the rest of the interval.
+type keyLimiter struct {
+	limit    int
+	interval time.Duration
+
+	// mu guards keys, start and warned. Records with a key already seen in the interval only take the read lock.
+	mu     sync.RWMutex
+	keys   map[string]struct{}
+	start  time.Time
+	warned bool
+
+	// fullUntil is the end of the interval, in Unix nanoseconds, once the limit has been reached in it, so that
+	// records with new keys get the overflow key without taking the write lock.
+	fullUntil atomic.Int64
+
+	// overflows counts the records given the overflow key since the limiter was created.
+	overflows atomic.Int64
+}
+
+func newKeyLimiter(cfg *SamplerConfig) *keyLimiter {
+	interval := cfg.KeyLimitInterval
+	if interval == 0 {
+		interval = defaultKeyLimitInterval
+	}
+	return &keyLimiter{
+		limit:    cfg.KeyLimit,
+		interval: interval,
+		keys:     make(map[string]struct{}),
+		start:    time.Now(),
+	}
+}
+
+// apply returns the key to sample a record with the given key by at now. It returns overflowKey if key is new and
+// the limit has been reached, and warn is true the first time that happens in an interval.
+func (l *keyLimiter) apply(key string, now time.Time) (limited string, warn bool) {
+	l.mu.RLock()
+	_, seen := l.keys[key]
+	current := now.Sub(l.start) < l.interval
+	l.mu.RUnlock()
+	if seen && current {
+		return key, false
+	}
+	if now.UnixNano() < l.fullUntil.Load() {
+		l.overflows.Add(1)
+		return overflowKey, false
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now.Sub(l.start) >= l.interval {
+		clear(l.keys)
+		l.start = now
+		l.warned = false
+	}
+
+	if _, ok := l.keys[key]; ok {
+		return key, false
+	}
+	if len(l.keys) < l.limit {
+		l.keys[key] = struct{}{}
+		return key, false
+	}
+
+	l.fullUntil.Store(l.start.Add(l.interval).UnixNano())
+	l.overflows.Add(1)
+	warn = !l.warned
+	l.warned = true
+	return overflowKey, warn
+}
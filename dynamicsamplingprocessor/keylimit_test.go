@@ -0,0 +1,116 @@
+package dynamicsamplingprocessor
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/collector/consumer/consumertest"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata/metricdatatest"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/honeycombio/opentelemetry-collector-configs/dynamicsamplingprocessor/internal/metadatatest"
+)
+
+func TestKeyLimiter(t *testing.T) {
+	l := newKeyLimiter(&SamplerConfig{KeyLimit: 2, KeyLimitInterval: time.Minute})
+	start := l.start
+
+	apply := func(key string, now time.Time) []any {
+		limited, warn := l.apply(key, now)
+		return []any{limited, warn}
+	}
+
+	assert.Equal(t, []any{"a", false}, apply("a", start))
+	assert.Equal(t, []any{"b", false}, apply("b", start))
+	assert.Equal(t, []any{overflowKey, true}, apply("c", start))
+	assert.Equal(t, []any{overflowKey, false}, apply("d", start.Add(time.Second)))
+	assert.Equal(t, []any{"a", false}, apply("a", start.Add(time.Second)))
+	assert.Equal(t, int64(2), l.overflows.Load())
+
+	// counting starts over after the interval
+	assert.Equal(t, []any{"c", false}, apply("c", start.Add(time.Minute)))
+	assert.Equal(t, []any{"d", false}, apply("d", start.Add(time.Minute)))
+	assert.Equal(t, []any{overflowKey, true}, apply("a", start.Add(time.Minute)))
+}
+
+func TestKeyLimiterFullSkipsWriteLock(t *testing.T) {
+	l := newKeyLimiter(&SamplerConfig{KeyLimit: 1, KeyLimitInterval: time.Minute})
+	start := l.start
+	limited, _ := l.apply("a", start)
+	require.Equal(t, "a", limited)
+	limited, _ = l.apply("b", start)
+	require.Equal(t, overflowKey, limited)
+
+	// a reader holding the lock blocks writers, so a new key only gets through if apply sticks to the read lock
+	l.mu.RLock()
+	applied := make(chan string)
+	go func() {
+		limited, _ := l.apply("c", start.Add(time.Second))
+		applied <- limited
+	}()
+	select {
+	case limited := <-applied:
+		assert.Equal(t, overflowKey, limited)
+	case <-time.After(5 * time.Second):
+		t.Fatal("apply took the write lock while the limit was reached")
+	}
+	l.mu.RUnlock()
+	assert.Equal(t, int64(2), l.overflows.Load())
+
+	// the interval rolling over lifts the limit
+	limited, _ = l.apply("c", start.Add(time.Minute))
+	assert.Equal(t, "c", limited)
+}
+
+func TestLogsProcessorKeyLimit(t *testing.T) {
+	tt := componenttest.NewTelemetry()
+	t.Cleanup(func() { require.NoError(t, tt.Shutdown(context.Background())) })
+	core, logs := observer.New(zapcore.WarnLevel)
+
+	cfg := createDefaultConfig().(*Config)
+	cfg.Sampler = StaticSampler
+	cfg.KeyFields = []string{"user.id"}
+	cfg.Static = StaticConfig{Default: 1}
+	cfg.KeyLimit = 2
+	cfg.DryRun = true
+
+	set := metadatatest.NewSettings(tt)
+	set.Logger = zap.New(core)
+	sink := new(consumertest.LogsSink)
+	lp, err := NewFactory().CreateLogs(context.Background(), set, cfg, sink)
+	require.NoError(t, err)
+	require.NoError(t, lp.Start(context.Background(), componenttest.NewNopHost()))
+
+	ld := plog.NewLogs()
+	records := ld.ResourceLogs().AppendEmpty().ScopeLogs().AppendEmpty().LogRecords()
+	for _, user := range []string{"a", "b", "c", "d", "a"} {
+		records.AppendEmpty().Attributes().PutStr("user.id", user)
+	}
+	require.NoError(t, lp.ConsumeLogs(context.Background(), ld))
+
+	var keys []string
+	got := sink.AllLogs()[0].ResourceLogs().At(0).ScopeLogs().At(0).LogRecords()
+	for i := 0; i < got.Len(); i++ {
+		key, _ := got.At(i).Attributes().Get(dryRunKeyAttribute)
+		keys = append(keys, key.Str())
+	}
+	assert.Equal(t, []string{"a", "b", overflowKey, overflowKey, "a"}, keys)
+
+	assert.Equal(t, 1, logs.FilterMessageSnippet("key limit reached").Len())
+	metadatatest.AssertEqualProcessorDynamicSamplerOverflowRecords(t, tt,
+		[]metricdata.DataPoint[int64]{
+			{Value: 2, Attributes: attribute.NewSet(attribute.String("sampler", "default"))},
+		},
+		metricdatatest.IgnoreTimestamp())
+
+	require.NoError(t, lp.Shutdown(context.Background()))
+}
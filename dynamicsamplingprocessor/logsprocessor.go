@@ -2,77 +2,196 @@ package dynamicsamplingprocessor
 
 import (
 	"context"
-	"math/rand"
-	"sort"
-	"strconv"
-	"strings"
+	"errors"
 
+	"github.com/open-telemetry/opentelemetry-collector-contrib/pkg/ottl/contexts/ottllog"
+	"go.opentelemetry.io/collector/component"
 	"go.opentelemetry.io/collector/consumer"
-	"go.opentelemetry.io/collector/pdata/pcommon"
 	"go.opentelemetry.io/collector/pdata/plog"
 	"go.opentelemetry.io/collector/processor"
 	"go.opentelemetry.io/collector/processor/processorhelper"
 	"go.uber.org/zap"
 
-	dynsampler "github.com/honeycombio/dynsampler-go"
+	"github.com/honeycombio/opentelemetry-collector-configs/dynamicsamplingprocessor/internal/metadata"
 )
 
 type logsProcessor struct {
-	sampler   dynsampler.Sampler
-	keyFields []string
-
-	logger *zap.Logger
+	decider             *decider[ottllog.TransformContext]
+	sampleRateAttribute string
+	weighted            bool
+	countBytes          bool
+	dryRun              bool
+	templates           *templateMiner
+
+	// summaries is nil unless summaries are enabled.
+	summaries *summarizer
+
+	state            *stateStore[ottllog.TransformContext]
+	budget           *budgetController
+	schedules        *scheduleController[ottllog.TransformContext]
+	debug            *debugSignal
+	debugServer      *debugServer
+	telemetryBuilder *metadata.TelemetryBuilder
+	logger           *zap.Logger
+
+	id               component.ID
+	goalSourceID     *component.ID
+	unsubscribeGoals func()
 }
 
 // newLogsProcessor returns a processor.LogsProcessor that will perform head sampling according to the given
 // configuration.
 func newLogsProcessor(ctx context.Context, set processor.Settings, nextConsumer consumer.Logs, cfg *Config) (processor.Logs, error) {
+	parser, err := newLogParser(set.TelemetrySettings)
+	if err != nil {
+		return nil, err
+	}
+
+	decider, err := newDecider(cfg, parser, set.TelemetrySettings)
+	if err != nil {
+		return nil, err
+	}
+
+	telemetryBuilder, err := metadata.NewTelemetryBuilder(set.TelemetrySettings)
+	if err != nil {
+		return nil, err
+	}
+	if err = registerSamplerCallbacks(telemetryBuilder, decider); err != nil {
+		telemetryBuilder.Shutdown()
+		return nil, err
+	}
+
 	lsp := &logsProcessor{
-		sampler:   getSampler(cfg),
-		keyFields: cfg.KeyFields,
-		logger:    set.Logger,
+		decider:             decider,
+		sampleRateAttribute: cfg.SampleRateAttribute,
+		weighted:            cfg.WeightByUpstreamSampleRate,
+		countBytes:          cfg.ThroughputUnit == ThroughputUnitBytes,
+		dryRun:              cfg.DryRun,
+		templates:           newTemplateMiner(&cfg.LogTemplates),
+		state:               newStateStore(decider, cfg, set.ID, "logs", set.Logger),
+		schedules:           newScheduleController(decider, cfg, set.Logger),
+		debug:               newDebugSignal("logs", decider),
+		telemetryBuilder:    telemetryBuilder,
+		logger:              set.Logger,
+		id:                  set.ID,
+		goalSourceID:        cfg.GoalSourceID,
+	}
+	if cfg.Summaries.Enabled {
+		lsp.summaries = newSummarizer(&cfg.Summaries, nextConsumer, set.Logger)
+	}
+	if lsp.sampleRateAttribute == "" {
+		lsp.sampleRateAttribute = defaultSampleRateAttribute
 	}
 
-	return processorhelper.NewLogsProcessor(
+	// the logs and traces processors created with the same ID share one budget and one debug page
+	lsp.budget = budgetControllers.acquire(set.ID, func() *budgetController { return newBudgetController(cfg, set.Logger) })
+	lsp.debugServer = debugServers.acquire(set.ID, func() *debugServer { return newDebugServer(cfg, set.ID, set.Logger) })
+
+	proc, err := processorhelper.NewLogs(
 		ctx,
 		set,
 		cfg,
 		nextConsumer,
 		lsp.processLogs,
-		processorhelper.WithCapabilities(consumer.Capabilities{MutatesData: true}))
+		processorhelper.WithCapabilities(consumer.Capabilities{MutatesData: true}),
+		processorhelper.WithStart(lsp.start),
+		processorhelper.WithShutdown(lsp.shutdown))
+	if err != nil {
+		// the processor is never shut down if it cannot be created, so release what it holds here
+		budgetControllers.release(set.ID)
+		debugServers.release(set.ID)
+		telemetryBuilder.Shutdown()
+		return nil, err
+	}
+	return proc, nil
 }
 
-func getSampler(cfg *Config) dynsampler.Sampler {
-	var sampler dynsampler.Sampler
-	if cfg.Sampler == EMADynamicSampler {
-		sampler = &dynsampler.EMASampleRate{
-			GoalSampleRate: cfg.GoalSampleRate,
-		}
-	} else {
-		sampler = &dynsampler.EMAThroughput{
-			GoalThroughputPerSec: cfg.GoalThroughputPerSecond,
-		}
+// start restores any saved sampler state, starts the samplers, subscribes to goal changes, starts following the
+// goal schedules, starts serving the debug page and starts adjusting the goals to the budget.
+func (lsp *logsProcessor) start(ctx context.Context, host component.Host) error {
+	if err := lsp.state.start(ctx, host); err != nil {
+		return err
+	}
+	if err := lsp.decider.start(); err != nil {
+		return err
+	}
+	if lsp.summaries != nil {
+		lsp.summaries.start()
+	}
+	unsubscribe, err := subscribeGoals(host, lsp.goalSourceID, lsp.id, lsp.decider)
+	if err != nil {
+		return err
 	}
+	lsp.unsubscribeGoals = unsubscribe
+	lsp.schedules.start()
+	if err := lsp.debugServer.attach(lsp.debug); err != nil {
+		return err
+	}
+	return lsp.budget.attach(host, lsp.decider, UsageSource.RecordedLogRecords)
+}
 
-	sampler.Start()
-	return sampler
+// shutdown sends the summaries of the current interval, saves the sampler state and stops the samplers. It is also
+// called when the processor was never started, or failed to start.
+func (lsp *logsProcessor) shutdown(ctx context.Context) error {
+	if lsp.unsubscribeGoals != nil {
+		lsp.unsubscribeGoals()
+	}
+	lsp.budget.detach(lsp.decider)
+	budgetControllers.release(lsp.id)
+	lsp.schedules.shutdown()
+	debugErr := lsp.debugServer.detach(ctx, lsp.debug)
+	debugServers.release(lsp.id)
+	lsp.telemetryBuilder.Shutdown()
+	err := debugErr
+	if lsp.summaries != nil {
+		err = errors.Join(err, lsp.summaries.shutdown(ctx))
+	}
+	err = errors.Join(err, lsp.state.shutdown(ctx))
+	return errors.Join(err, lsp.decider.stop())
 }
 
 func (lsp *logsProcessor) processLogs(ctx context.Context, logsData plog.Logs) (plog.Logs, error) {
+	var kept, dropped int64
+	fields := &logLookup{templates: lsp.templates}
+	lookup := fields.field
 	logsData.ResourceLogs().RemoveIf(func(rl plog.ResourceLogs) bool {
+		resource := rl.Resource()
 		rl.ScopeLogs().RemoveIf(func(ill plog.ScopeLogs) bool {
+			scope := ill.Scope()
 			ill.LogRecords().RemoveIf(func(l plog.LogRecord) bool {
-				key := makeDynsampleKey(l, lsp.keyFields)
-				sampleRate := lsp.sampler.GetSampleRate(key)
-
-				// example: with sampleRate=10, there is a 10% chance rand.Intn(10) == 0
-				keep := rand.Intn(sampleRate) == 0
-				if keep {
-					attrs := l.Attributes()
-					attrs.PutInt("SampleRate", int64(sampleRate))
+				attrs := l.Attributes()
+				upstreamRate := upstreamSampleRate(attrs, lsp.sampleRateAttribute)
+				count := 1
+				if lsp.countBytes {
+					count = logRecordSize(l)
+				}
+				if lsp.weighted {
+					count *= upstreamRate
+				}
+
+				tCtx := ottllog.NewTransformContext(l, scope, resource, ill, rl)
+				fields.resource, fields.scope, fields.lr = resource, scope, l
+				decision := lsp.decider.decide(ctx, tCtx, lookup, count)
+				if decision.sampleRate > 0 {
+					lsp.telemetryBuilder.ProcessorDynamicSamplerSampleRate.Record(ctx, int64(decision.sampleRate))
+				}
+				if decision.keep {
+					kept++
+				} else {
+					dropped++
+				}
+				if lsp.summaries != nil {
+					lsp.summaries.record(decision)
 				}
 
-				return !keep
+				if lsp.dryRun {
+					annotateDryRun(attrs, decision, upstreamRate)
+					return false
+				}
+				if decision.keep {
+					attrs.PutInt(lsp.sampleRateAttribute, int64(decision.sampleRate)*int64(upstreamRate))
+				}
+				return !decision.keep
 			})
 			// Filter out empty ScopeLogs
 			return ill.LogRecords().Len() == 0
@@ -80,48 +199,11 @@ func (lsp *logsProcessor) processLogs(ctx context.Context, logsData plog.Logs) (
 		// Filter out empty ResourceLogs
 		return rl.ScopeLogs().Len() == 0
 	})
+	recordSampled(ctx, lsp.telemetryBuilder.ProcessorDynamicSamplerCountLogsSampled, kept, dropped)
+	lsp.debug.record(kept, dropped)
+
 	if logsData.ResourceLogs().Len() == 0 {
 		return logsData, processorhelper.ErrSkipProcessingData
 	}
 	return logsData, nil
 }
-
-func makeDynsampleKey(l plog.LogRecord, keyFields []string) string {
-	key := make([]string, len(keyFields))
-	var sb strings.Builder
-
-	attrs := l.Attributes()
-	for i, field := range keyFields {
-		if val, ok := attrs.Get(field); ok {
-			switch val.Type() {
-			case pcommon.ValueTypeBool:
-				key[i] = strconv.FormatBool(val.Bool())
-			case pcommon.ValueTypeInt:
-				key[i] = strconv.FormatInt(val.Int(), 10)
-			case pcommon.ValueTypeDouble:
-				key[i] = strconv.FormatFloat(val.Double(), 'E', -1, 64)
-			case pcommon.ValueTypeStr:
-				key[i] = val.Str()
-			default:
-				continue
-			}
-		}
-	}
-
-	sort.Strings(key)
-
-	estimatedLength := 0
-	for _, k := range key {
-		estimatedLength += len(k) + 1 // underscore
-	}
-	sb.Grow(estimatedLength)
-
-	for i, k := range key {
-		if i > 0 {
-			sb.WriteByte('_')
-		}
-		sb.WriteString(k)
-	}
-
-	return sb.String()
-}
@@ -0,0 +1,197 @@
+package dynamicsamplingprocessor
+
+import (
+	"hash/fnv"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+)
+
+const (
+	// defaultSimilarityThreshold is used when log_templates similarity_threshold is not set.
+	defaultSimilarityThreshold = 0.5
+
+	// defaultMaxTemplates is used when log_templates max_templates is not set.
+	defaultMaxTemplates = 1000
+
+	// wildcardToken replaces the tokens that differ between the bodies clustered into a template.
+	wildcardToken = "<*>"
+
+	// templateMinerShards is the number of shards the template groups are spread over, so that bodies of different
+	// groups are clustered in parallel.
+	templateMinerShards = 16
+)
+
+// maskers replace the variable parts of a log body, in order, before it is clustered. Quoted strings are masked
+// first so that their contents are not masked separately, and numbers last so that they do not break up UUIDs,
+// IP addresses and hex IDs.
+var maskers = []struct {
+	pattern *regexp.Regexp
+	replace func(string) string
+}{
+	{regexp.MustCompile(`"(?:[^"\\]|\\.)*"|'(?:[^'\\]|\\.)*'`), mask("<str>")},
+	{regexp.MustCompile(`(?i)\b[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}\b`), mask("<uuid>")},
+	{regexp.MustCompile(`\b\d{1,3}(?:\.\d{1,3}){3}(?::\d+)?\b`), mask("<ip>")},
+	{regexp.MustCompile(`(?i)\b(?:0x[0-9a-f]+|[0-9a-f]{8,})\b`), maskHex},
+	{regexp.MustCompile(`\d+(?:\.\d+)?`), mask("<num>")},
+}
+
+func mask(replacement string) func(string) string {
+	return func(string) string { return replacement }
+}
+
+// maskHex masks hex IDs. Runs of hex letters without a digit are left alone, as they are more likely to be words.
+func maskHex(s string) string {
+	if strings.HasPrefix(s, "0x") || strings.HasPrefix(s, "0X") || strings.ContainsAny(s, "0123456789") {
+		return "<hex>"
+	}
+	return s
+}
+
+// maskBody returns the tokens of a log body with numbers, hex IDs, UUIDs, IP addresses and quoted strings masked.
+func maskBody(body string) []string {
+	for _, m := range maskers {
+		body = m.pattern.ReplaceAllStringFunc(body, m.replace)
+	}
+	return strings.Fields(body)
+}
+
+// logTemplate is a cluster of log bodies. Tokens that differ between the bodies in the cluster are wildcards.
+type logTemplate struct {
+	id     string
+	tokens []string
+}
+
+// templateGroup identifies the templates a masked body is compared against: those with the same number of tokens
+// and the same first token.
+type templateGroup struct {
+	length int
+	first  string
+}
+
+// templateMiner clusters log bodies into templates in the manner of the Drain algorithm. Bodies are masked and
+// split into tokens, and compared against the templates with the same number of tokens and first token. A body
+// joins the most similar template if enough of its tokens match, turning the tokens that differ into wildcards, and
+// otherwise starts a new template. A template keeps the ID it was given when it was created.
+type templateMiner struct {
+	threshold    float64
+	maxTemplates int
+
+	shards []templateShard
+
+	// templates counts the templates kept across all shards, up to maxTemplates.
+	templates atomic.Int64
+}
+
+// templateShard holds the template groups of one shard of a templateMiner.
+type templateShard struct {
+	mu     sync.Mutex
+	groups map[templateGroup][]*logTemplate
+}
+
+func newTemplateMiner(cfg *LogTemplatesConfig) *templateMiner {
+	threshold := cfg.SimilarityThreshold
+	if threshold == 0 {
+		threshold = defaultSimilarityThreshold
+	}
+	maxTemplates := cfg.MaxTemplates
+	if maxTemplates == 0 {
+		maxTemplates = defaultMaxTemplates
+	}
+	m := &templateMiner{
+		threshold:    threshold,
+		maxTemplates: maxTemplates,
+		shards:       make([]templateShard, templateMinerShards),
+	}
+	for i := range m.shards {
+		m.shards[i].groups = make(map[templateGroup][]*logTemplate)
+	}
+	return m
+}
+
+// shard returns the shard holding the templates of group.
+func (m *templateMiner) shard(group templateGroup) *templateShard {
+	return &m.shards[keyShard(group.first, len(m.shards))]
+}
+
+// templateID returns the ID of the template of the log body, or false if the body has no tokens.
+func (m *templateMiner) templateID(body string) (string, bool) {
+	tokens := maskBody(body)
+	if len(tokens) == 0 {
+		return "", false
+	}
+	group := templateGroup{length: len(tokens), first: tokens[0]}
+
+	shard := m.shard(group)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	var best *logTemplate
+	var bestSimilarity float64
+	for _, t := range shard.groups[group] {
+		if similarity := t.similarity(tokens); similarity > bestSimilarity {
+			best, bestSimilarity = t, similarity
+		}
+	}
+	if best != nil && bestSimilarity >= m.threshold {
+		best.merge(tokens)
+		return best.id, true
+	}
+
+	t := &logTemplate{id: templateID(tokens), tokens: tokens}
+	if m.templates.Add(1) <= int64(m.maxTemplates) {
+		shard.groups[group] = append(shard.groups[group], t)
+	} else {
+		m.templates.Add(-1)
+	}
+	return t.id, true
+}
+
+// bodyTemplate returns the template ID of the body of lr, as the value of the body_template pseudo-field. Bodies
+// that are not strings have no template.
+func (m *templateMiner) bodyTemplate(lr plog.LogRecord) (pcommon.Value, bool) {
+	if lr.Body().Type() != pcommon.ValueTypeStr {
+		return pcommon.Value{}, false
+	}
+	id, ok := m.templateID(lr.Body().Str())
+	if !ok {
+		return pcommon.Value{}, false
+	}
+	return pcommon.NewValueStr(id), true
+}
+
+// similarity returns the fraction of tokens that match the template. Wildcards match any token.
+func (t *logTemplate) similarity(tokens []string) float64 {
+	var same int
+	for i, token := range tokens {
+		if t.tokens[i] == token || t.tokens[i] == wildcardToken {
+			same++
+		}
+	}
+	return float64(same) / float64(len(tokens))
+}
+
+// merge turns the tokens of the template that differ from tokens into wildcards.
+func (t *logTemplate) merge(tokens []string) {
+	for i, token := range tokens {
+		if t.tokens[i] != token {
+			t.tokens[i] = wildcardToken
+		}
+	}
+}
+
+// templateID derives a template ID from the tokens the template was created with, so that every collector gives
+// the same ID to a template first seen with the same masked body.
+func templateID(tokens []string) string {
+	h := fnv.New64a()
+	for _, token := range tokens {
+		_, _ = h.Write([]byte(token))
+		_, _ = h.Write([]byte{' '})
+	}
+	return strconv.FormatUint(h.Sum64(), 16)
+}
@@ -0,0 +1,181 @@
+package dynamicsamplingprocessor
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/collector/consumer/consumertest"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/processor/processortest"
+)
+
+func TestMaskBody(t *testing.T) {
+	tests := []struct {
+		body     string
+		expected []string
+	}{
+		{
+			body:     "request 42 took 12.5ms",
+			expected: []string{"request", "<num>", "took", "<num>ms"},
+		},
+		{
+			body:     "user id=550e8400-e29b-41d4-a716-446655440000 connected from 10.1.2.3:8080",
+			expected: []string{"user", "id=<uuid>", "connected", "from", "<ip>"},
+		},
+		{
+			body:     "commit 9fceb02d0ae598e95dc970b74767f19372d61af8 at 0xdeadbeef",
+			expected: []string{"commit", "<hex>", "at", "<hex>"},
+		},
+		{
+			body:     `lookup of "some name" and 'other name' failed`,
+			expected: []string{"lookup", "of", "<str>", "and", "<str>", "failed"},
+		},
+		{
+			body:     "deadbeefcafe is not a hex ID without a digit",
+			expected: []string{"deadbeefcafe", "is", "not", "a", "hex", "ID", "without", "a", "digit"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.body, func(t *testing.T) {
+			assert.Equal(t, tt.expected, maskBody(tt.body))
+		})
+	}
+}
+
+func TestTemplateMiner(t *testing.T) {
+	m := newTemplateMiner(&LogTemplatesConfig{})
+
+	templateID := func(body string) string {
+		id, ok := m.templateID(body)
+		require.True(t, ok)
+		return id
+	}
+
+	loginAlice := templateID("user alice logged in from 10.0.0.1")
+	assert.Equal(t, loginAlice, templateID("user bob logged in from 10.0.0.2"))
+	assert.Equal(t, loginAlice, templateID("user carol logged in from 10.0.0.3"))
+	group := templateGroup{length: 6, first: "user"}
+	assert.Equal(t, []string{"user", "<*>", "logged", "in", "from", "<ip>"}, m.shard(group).groups[group][0].tokens)
+
+	assert.NotEqual(t, loginAlice, templateID("user alice changed her password twice"))
+	assert.NotEqual(t, loginAlice, templateID("user alice logged in"))
+	assert.NotEqual(t, loginAlice, templateID("cache alice logged in from 10.0.0.1"))
+
+	_, ok := m.templateID("  ")
+	assert.False(t, ok)
+}
+
+func TestTemplateMinerMaxTemplates(t *testing.T) {
+	m := newTemplateMiner(&LogTemplatesConfig{MaxTemplates: 1})
+
+	first, _ := m.templateID("connection opened")
+	second, _ := m.templateID("cache miss for key")
+	again, _ := m.templateID("cache miss for key")
+
+	assert.NotEqual(t, first, second)
+	assert.Equal(t, second, again)
+	assert.Equal(t, int64(1), m.templates.Load())
+}
+
+func TestTemplateMinerConcurrent(t *testing.T) {
+	m := newTemplateMiner(&LogTemplatesConfig{MaxTemplates: 10})
+
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// 20 groups, each with a different first token, clustered in a different order by each worker
+			for j := range 100 {
+				m.templateID(strings.Repeat("x", (i+j)%20+1) + " started")
+			}
+		}()
+	}
+	wg.Wait()
+
+	var kept int
+	for i := range m.shards {
+		for _, templates := range m.shards[i].groups {
+			kept += len(templates)
+		}
+	}
+	assert.Equal(t, 10, kept)
+	assert.Equal(t, int64(10), m.templates.Load())
+}
+
+func TestLogsProcessorBodyTemplateKey(t *testing.T) {
+	cfg := createDefaultConfig().(*Config)
+	cfg.Sampler = StaticSampler
+	cfg.Static = StaticConfig{Default: 1}
+	cfg.KeyFields = []string{bodyTemplateField}
+	cfg.DryRun = true
+
+	sink := new(consumertest.LogsSink)
+	lp, err := NewFactory().CreateLogs(context.Background(), processortest.NewNopSettings(typ), cfg, sink)
+	require.NoError(t, err)
+	require.NoError(t, lp.Start(context.Background(), componenttest.NewNopHost()))
+
+	logs := plog.NewLogs()
+	records := logs.ResourceLogs().AppendEmpty().ScopeLogs().AppendEmpty().LogRecords()
+	records.AppendEmpty().Body().SetStr("GET /users/12 returned 200 in 3ms")
+	records.AppendEmpty().Body().SetStr("GET /users/98 returned 200 in 17ms")
+	records.AppendEmpty().Body().SetStr("worker 3 restarted")
+	records.AppendEmpty().Body().SetEmptyMap()
+
+	require.NoError(t, lp.ConsumeLogs(context.Background(), logs))
+	require.NoError(t, lp.Shutdown(context.Background()))
+
+	var keys []string
+	got := sink.AllLogs()[0].ResourceLogs().At(0).ScopeLogs().At(0).LogRecords()
+	for i := 0; i < got.Len(); i++ {
+		key, _ := got.At(i).Attributes().Get(dryRunKeyAttribute)
+		keys = append(keys, key.Str())
+	}
+	request := templateID([]string{"GET", "/users/<num>", "returned", "<num>", "in", "<num>ms"})
+	worker := templateID([]string{"worker", "<num>", "restarted"})
+	assert.Equal(t, []string{request, request, worker, missingKeyPart}, keys)
+}
+
+func BenchmarkConsumeLogs(b *testing.B) {
+	for name, keyFields := range map[string][]string{
+		"attributes":    {"service.name", "severity_text", "http.route"},
+		"body_template": {"service.name", bodyTemplateField},
+	} {
+		b.Run(name, func(b *testing.B) {
+			cfg := createDefaultConfig().(*Config)
+			cfg.Sampler = StaticSampler
+			cfg.Static = StaticConfig{Default: 1}
+			cfg.KeyFields = keyFields
+			cfg.DryRun = true
+
+			lp, err := NewFactory().CreateLogs(context.Background(), processortest.NewNopSettings(typ), cfg, consumertest.NewNop())
+			require.NoError(b, err)
+			require.NoError(b, lp.Start(context.Background(), componenttest.NewNopHost()))
+			b.Cleanup(func() { require.NoError(b, lp.Shutdown(context.Background())) })
+
+			// dry run keeps every record, so the same batch can be sent on every iteration
+			logs := plog.NewLogs()
+			rl := logs.ResourceLogs().AppendEmpty()
+			rl.Resource().Attributes().PutStr("service.name", "checkout")
+			records := rl.ScopeLogs().AppendEmpty().LogRecords()
+			for i := range 100 {
+				lr := records.AppendEmpty()
+				lr.SetSeverityText("INFO")
+				lr.Attributes().PutStr("http.route", "/cart/{id}")
+				lr.Body().SetStr("GET /cart/" + strconv.Itoa(i) + " returned 200")
+			}
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				require.NoError(b, lp.ConsumeLogs(context.Background(), logs))
+			}
+		})
+	}
+}
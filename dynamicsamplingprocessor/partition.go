@@ -0,0 +1,317 @@
+package dynamicsamplingprocessor
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	dynsampler "github.com/honeycombio/dynsampler-go"
+)
+
+const (
+	// defaultPartitionIdleTimeout is used when partition_idle_timeout is not set.
+	defaultPartitionIdleTimeout = 5 * time.Minute
+
+	// defaultMaxPartitions is used when max_partitions is not set.
+	defaultMaxPartitions = 1000
+)
+
+// partitionedSampler keeps a separate dynsampler-go sampler for every value of the partition field. Samplers
+// are created the first time a value is seen and are stopped and removed once the partition has been idle for
+// longer than idleTimeout. Once maxPartitions partitions are running, values without a goal override share the
+// overflowKey partition.
+type partitionedSampler struct {
+	partitionBy   string
+	idleTimeout   time.Duration
+	maxPartitions int
+
+	// mu guards cfg, generation, partitions and restored. Records for a running partition only take the read
+	// lock. Samplers are started and stopped without holding mu.
+	mu         sync.RWMutex
+	cfg        SamplerConfig
+	generation int
+	partitions map[string]*partition
+
+	// restored holds saved sampler states, by partition value, for partitions that have not been seen since the
+	// state was loaded.
+	restored map[string]json.RawMessage
+
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+type partition struct {
+	sampler dynsampler.Sampler
+
+	// lastSeen is the time, in Unix nanoseconds, the partition last sampled a record.
+	lastSeen atomic.Int64
+}
+
+func newPartitionedSampler(cfg *SamplerConfig) *partitionedSampler {
+	idleTimeout := cfg.PartitionIdleTimeout
+	if idleTimeout == 0 {
+		idleTimeout = defaultPartitionIdleTimeout
+	}
+	maxPartitions := cfg.MaxPartitions
+	if maxPartitions == 0 {
+		maxPartitions = defaultMaxPartitions
+	}
+	return &partitionedSampler{
+		cfg:           *cfg,
+		partitionBy:   cfg.PartitionBy,
+		idleTimeout:   idleTimeout,
+		maxPartitions: maxPartitions,
+		partitions:    make(map[string]*partition),
+	}
+}
+
+// start starts reaping idle partitions once per idleTimeout.
+func (p *partitionedSampler) start() {
+	p.done = make(chan struct{})
+	p.wg.Add(1)
+	go p.reapLoop(p.done)
+}
+
+func (p *partitionedSampler) reapLoop(done chan struct{}) {
+	defer p.wg.Done()
+
+	ticker := time.NewTicker(p.idleTimeout)
+	defer ticker.Stop()
+	for {
+		select {
+		case now := <-ticker.C:
+			p.reap(now)
+		case <-done:
+			return
+		}
+	}
+}
+
+// partitionValue returns the partition the record described by lookup belongs to. Records without a value
+// for the partition field share the "" partition.
+func (p *partitionedSampler) partitionValue(lookup fieldLookup) string {
+	if val, ok := lookup(p.partitionBy); ok {
+		if s, ok := keyPart(val); ok {
+			return s
+		}
+	}
+	return ""
+}
+
+// get returns the sampler for the given partition value, creating it if needed. Values seen once maxPartitions
+// partitions are running get the sampler of the overflowKey partition, unless they have a goal override.
+func (p *partitionedSampler) get(value string, now time.Time) (dynsampler.Sampler, error) {
+	p.mu.RLock()
+	part, ok := p.partitions[value]
+	if !ok && p.full(value) {
+		value = overflowKey
+		part, ok = p.partitions[value]
+	}
+	p.mu.RUnlock()
+	if ok {
+		part.lastSeen.Store(now.UnixNano())
+		return part.sampler, nil
+	}
+
+	for {
+		p.mu.Lock()
+		cfg, generation := p.partitionConfig(value), p.generation
+		state, restored := p.restored[value]
+		p.mu.Unlock()
+
+		sampler, err := startSampler(&cfg, state)
+		if err != nil && restored {
+			// start afresh rather than not at all if the saved state cannot be loaded
+			sampler, err = getSampler(&cfg)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		p.mu.Lock()
+		part, ok := p.partitions[value]
+		switch {
+		case ok:
+			// another record started the partition first
+		case generation != p.generation:
+			// the config changed while the sampler was starting, so start it again with the new config
+		case p.full(value):
+			value = overflowKey
+		default:
+			part = &partition{sampler: sampler}
+			p.partitions[value] = part
+			delete(p.restored, value)
+			sampler = nil
+			ok = true
+		}
+		p.mu.Unlock()
+
+		if sampler != nil {
+			_ = sampler.Stop()
+		}
+		if ok {
+			part.lastSeen.Store(now.UnixNano())
+			return part.sampler, nil
+		}
+	}
+}
+
+// full reports whether value would exceed maxPartitions. Values with a goal override are never turned away. The
+// caller must hold p.mu.
+func (p *partitionedSampler) full(value string) bool {
+	if value == overflowKey {
+		return false
+	}
+	if _, ok := p.cfg.Partitions[value]; ok {
+		return false
+	}
+	return len(p.partitions) >= p.maxPartitions
+}
+
+// partitionConfig returns the config the sampler of the given partition is created from. The caller must hold
+// p.mu.
+func (p *partitionedSampler) partitionConfig(value string) SamplerConfig {
+	cfg := p.cfg
+	if override, ok := p.cfg.Partitions[value]; ok {
+		cfg = cfg.withGoal(override.GoalSampleRate, float64(override.GoalThroughputPerSecond))
+	}
+	return cfg
+}
+
+// reap stops and removes the samplers of partitions that have not been used since idleTimeout before now, and
+// drops saved states that were not claimed by a partition in that time.
+func (p *partitionedSampler) reap(now time.Time) {
+	var idle []dynsampler.Sampler
+	p.mu.Lock()
+	p.restored = nil
+	for value, part := range p.partitions {
+		if now.Sub(time.Unix(0, part.lastSeen.Load())) > p.idleTimeout {
+			idle = append(idle, part.sampler)
+			delete(p.partitions, value)
+		}
+	}
+	p.mu.Unlock()
+
+	for _, sampler := range idle {
+		_ = sampler.Stop()
+	}
+}
+
+// setConfig replaces the config that partition samplers are created from. The samplers of running partitions
+// without a goal override are replaced by samplers started with the new config and the state of the samplers they
+// replace, which are stopped once the replacements are in place.
+func (p *partitionedSampler) setConfig(cfg SamplerConfig) error {
+	p.mu.Lock()
+	p.cfg = cfg
+	p.generation++
+	running := make(map[string]*partition, len(p.partitions))
+	for value, part := range p.partitions {
+		if _, ok := cfg.Partitions[value]; !ok {
+			running[value] = part
+		}
+	}
+	p.mu.Unlock()
+
+	var errs error
+	replaced := make(map[string]dynsampler.Sampler, len(running))
+	for value, part := range running {
+		sampler, err := replaceSampler(part.sampler, &cfg)
+		if err != nil {
+			errs = errors.Join(errs, fmt.Errorf("partition %q: %w", value, err))
+			continue
+		}
+		replaced[value] = sampler
+	}
+
+	var old []dynsampler.Sampler
+	p.mu.Lock()
+	for value, sampler := range replaced {
+		part, ok := p.partitions[value]
+		if !ok || part != running[value] {
+			// the partition was reaped while its replacement was starting
+			old = append(old, sampler)
+			continue
+		}
+		replacement := &partition{sampler: sampler}
+		replacement.lastSeen.Store(part.lastSeen.Load())
+		p.partitions[value] = replacement
+		old = append(old, part.sampler)
+	}
+	p.mu.Unlock()
+
+	for _, sampler := range old {
+		_ = sampler.Stop()
+	}
+	return errs
+}
+
+// stop stops reaping and stops and removes the samplers of all partitions.
+func (p *partitionedSampler) stop() error {
+	if p.done != nil {
+		close(p.done)
+		p.wg.Wait()
+		p.done = nil
+	}
+
+	p.mu.Lock()
+	partitions := p.partitions
+	p.partitions = make(map[string]*partition)
+	p.mu.Unlock()
+
+	var errs error
+	for _, part := range partitions {
+		errs = errors.Join(errs, part.sampler.Stop())
+	}
+	return errs
+}
+
+// keyspaceSize returns the number of keys tracked by the samplers of all partitions.
+func (p *partitionedSampler) keyspaceSize() int64 {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	var size int64
+	for _, part := range p.partitions {
+		size += part.sampler.GetMetrics("")["keyspace_size"]
+	}
+	return size
+}
+
+// saveState returns the state of the sampler of every partition as a JSON object keyed by partition value, or nil
+// if none of the samplers have state to save.
+func (p *partitionedSampler) saveState() ([]byte, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	states := make(map[string]json.RawMessage, len(p.partitions))
+	for value, part := range p.partitions {
+		state, err := part.sampler.SaveState()
+		if err != nil {
+			return nil, err
+		}
+		if len(state) > 0 {
+			states[value] = state
+		}
+	}
+	if len(states) == 0 {
+		return nil, nil
+	}
+	return json.Marshal(states)
+}
+
+// loadState keeps the partition states saved by saveState until each partition is next seen, when its sampler is
+// restored from the state before it is started.
+func (p *partitionedSampler) loadState(state []byte) error {
+	var states map[string]json.RawMessage
+	if err := json.Unmarshal(state, &states); err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.restored = states
+	return nil
+}
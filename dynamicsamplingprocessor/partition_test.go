@@ -0,0 +1,165 @@
+package dynamicsamplingprocessor
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+
+	dynsampler "github.com/honeycombio/dynsampler-go"
+)
+
+func TestPartitionedSamplerGoals(t *testing.T) {
+	cfg := SamplerConfig{
+		Sampler:                 EMAThroughputSampler,
+		KeyFields:               []string{"key1"},
+		GoalThroughputPerSecond: 100,
+		PartitionBy:             "service.name",
+		Partitions: map[string]PartitionConfig{
+			"checkout": {GoalThroughputPerSecond: 500},
+		},
+	}
+	p := newPartitionedSampler(&cfg)
+	t.Cleanup(func() { require.NoError(t, p.stop()) })
+	now := time.Now()
+
+	checkout, err := p.get("checkout", now)
+	require.NoError(t, err)
+	payments, err := p.get("payments", now)
+	require.NoError(t, err)
+
+	require.IsType(t, &dynsampler.EMAThroughput{}, checkout)
+	require.IsType(t, &dynsampler.EMAThroughput{}, payments)
+	assert.Equal(t, 500, checkout.(*dynsampler.EMAThroughput).GoalThroughputPerSec)
+	assert.Equal(t, 100, payments.(*dynsampler.EMAThroughput).GoalThroughputPerSec)
+
+	again, err := p.get("checkout", now)
+	require.NoError(t, err)
+	assert.Same(t, checkout, again)
+}
+
+func TestPartitionedSamplerReapsIdlePartitions(t *testing.T) {
+	cfg := SamplerConfig{
+		Sampler:              StaticSampler,
+		KeyFields:            []string{"key1"},
+		PartitionBy:          "service.name",
+		PartitionIdleTimeout: time.Minute,
+	}
+	p := newPartitionedSampler(&cfg)
+	t.Cleanup(func() { require.NoError(t, p.stop()) })
+	start := time.Now()
+
+	_, err := p.get("idle", start)
+	require.NoError(t, err)
+	_, err = p.get("busy", start)
+	require.NoError(t, err)
+
+	_, err = p.get("busy", start.Add(50*time.Second))
+	require.NoError(t, err)
+	p.reap(start.Add(50 * time.Second))
+	assert.Len(t, p.partitions, 2)
+
+	p.reap(start.Add(90 * time.Second))
+	assert.Contains(t, p.partitions, "busy")
+	assert.NotContains(t, p.partitions, "idle")
+}
+
+func TestPartitionedSamplerMaxPartitions(t *testing.T) {
+	cfg := SamplerConfig{
+		Sampler:                 EMAThroughputSampler,
+		KeyFields:               []string{"key1"},
+		GoalThroughputPerSecond: 100,
+		PartitionBy:             "service.name",
+		Partitions: map[string]PartitionConfig{
+			"checkout": {GoalThroughputPerSecond: 500},
+		},
+		MaxPartitions:        2,
+		PartitionIdleTimeout: time.Minute,
+	}
+	p := newPartitionedSampler(&cfg)
+	t.Cleanup(func() { require.NoError(t, p.stop()) })
+	now := time.Now()
+
+	first, err := p.get("first", now)
+	require.NoError(t, err)
+	second, err := p.get("second", now)
+	require.NoError(t, err)
+	assert.NotSame(t, first, second)
+
+	third, err := p.get("third", now)
+	require.NoError(t, err)
+	fourth, err := p.get("fourth", now)
+	require.NoError(t, err)
+	assert.Same(t, third, fourth, "values beyond the cap share one partition")
+	assert.Contains(t, p.partitions, overflowKey)
+	assert.NotContains(t, p.partitions, "third")
+
+	checkout, err := p.get("checkout", now)
+	require.NoError(t, err)
+	assert.NotSame(t, third, checkout, "values with a goal override get their own partition")
+	assert.Equal(t, 500, checkout.(*dynsampler.EMAThroughput).GoalThroughputPerSec)
+
+	again, err := p.get("first", now)
+	require.NoError(t, err)
+	assert.Same(t, first, again)
+
+	// once idle partitions are reaped there is room for new values again
+	_, err = p.get("first", now.Add(50*time.Second))
+	require.NoError(t, err)
+	p.reap(now.Add(90 * time.Second))
+	fifth, err := p.get("fifth", now.Add(90*time.Second))
+	require.NoError(t, err)
+	assert.NotSame(t, third, fifth)
+	assert.Contains(t, p.partitions, "fifth")
+}
+
+func TestPartitionedSamplerConcurrentGet(t *testing.T) {
+	cfg := SamplerConfig{
+		Sampler:       StaticSampler,
+		KeyFields:     []string{"key1"},
+		PartitionBy:   "service.name",
+		MaxPartitions: 4,
+	}
+	p := newPartitionedSampler(&cfg)
+	p.start()
+	t.Cleanup(func() { require.NoError(t, p.stop()) })
+	now := time.Now()
+
+	var wg sync.WaitGroup
+	samplers := make([]dynsampler.Sampler, 32)
+	for i := range samplers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sampler, err := p.get(strconv.Itoa(i%8), now)
+			assert.NoError(t, err)
+			samplers[i] = sampler
+		}()
+	}
+	wg.Wait()
+
+	assert.LessOrEqual(t, len(p.partitions), 5, "at most max_partitions and the overflow partition")
+	for i, sampler := range samplers {
+		running, err := p.get(strconv.Itoa(i%8), now)
+		require.NoError(t, err)
+		assert.Same(t, running, sampler, "every record of a value gets the same running sampler")
+	}
+}
+
+func TestPartitionValue(t *testing.T) {
+	p := newPartitionedSampler(&SamplerConfig{Sampler: StaticSampler, PartitionBy: "service.name"})
+
+	resourceAttrs := pcommon.NewMap()
+	resourceAttrs.PutStr("service.name", "checkout")
+	lookup := func(field string) (pcommon.Value, bool) {
+		return getAttribute(field, resourceAttrs, pcommon.NewMap(), pcommon.NewMap())
+	}
+	assert.Equal(t, "checkout", p.partitionValue(lookup))
+
+	missing := func(string) (pcommon.Value, bool) { return pcommon.Value{}, false }
+	assert.Equal(t, "", p.partitionValue(missing))
+}
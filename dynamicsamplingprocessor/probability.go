@@ -0,0 +1,95 @@
+package dynamicsamplingprocessor
+
+import (
+	"math"
+	"strings"
+
+	"github.com/open-telemetry/opentelemetry-collector-contrib/pkg/sampling"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+// sampleRateToThreshold returns the OpenTelemetry sampling threshold that keeps one in sampleRate spans.
+func sampleRateToThreshold(sampleRate int) sampling.Threshold {
+	threshold, err := sampling.ProbabilityToThreshold(1 / float64(sampleRate))
+	if err != nil {
+		// only possible for rates above 2^56
+		return sampling.NeverSampleThreshold
+	}
+	return threshold
+}
+
+// parseTraceState returns the OpenTelemetry values of the span's W3C tracestate. An invalid tracestate is
+// treated as empty.
+func parseTraceState(span ptrace.Span) (sampling.W3CTraceState, bool) {
+	w3c, err := sampling.NewW3CTraceState(span.TraceState().AsRaw())
+	if err != nil {
+		return sampling.W3CTraceState{}, false
+	}
+	return w3c, true
+}
+
+// spanRandomness returns the randomness of the span's trace, taken from the rv value of the tracestate when
+// present and from the trace ID otherwise.
+func spanRandomness(span ptrace.Span) sampling.Randomness {
+	if w3c, ok := parseTraceState(span); ok {
+		if rnd, ok := w3c.OTelValue().RValueRandomness(); ok {
+			return rnd
+		}
+	}
+	return sampling.TraceIDToRandomness(span.TraceID())
+}
+
+// probabilityDecision replaces the keep decision made for d.sampleRate with a consistent probability sampling
+// decision for the trace the span belongs to. Decisions that drop a trace outright are left untouched.
+func probabilityDecision(d decision, span ptrace.Span) decision {
+	if d.sampleRate == 0 {
+		return d
+	}
+	d.keep = sampleRateToThreshold(d.sampleRate).ShouldSample(spanRandomness(span))
+	return d
+}
+
+// updateTraceStateThreshold records the sampling threshold for sampleRate in the th value of the span's
+// tracestate. A threshold that was already lower in probability is kept. A threshold that the span's randomness
+// does not pass is inconsistent and is replaced. It returns the sample rate of the threshold left in the
+// tracestate, and whether the span already had a consistent threshold. When it had, the rate accounts for upstream
+// sampling and is the rate the span stands for.
+func updateTraceStateThreshold(span ptrace.Span, sampleRate int) (rate int, upstream bool) {
+	w3c, ok := parseTraceState(span)
+	if !ok {
+		w3c = sampling.W3CTraceState{}
+	}
+	otts := w3c.OTelValue()
+
+	threshold := sampleRateToThreshold(sampleRate)
+	if existing, ok := otts.TValueThreshold(); ok {
+		if !existing.ShouldSample(spanRandomness(span)) {
+			otts.ClearTValue()
+		} else {
+			upstream = true
+			if sampling.ThresholdGreater(existing, threshold) {
+				return thresholdToSampleRate(existing), true
+			}
+		}
+	}
+	if err := otts.UpdateTValueWithSampling(threshold); err != nil {
+		return sampleRate, upstream
+	}
+
+	var sb strings.Builder
+	if err := w3c.Serialize(&sb); err != nil {
+		return sampleRate, upstream
+	}
+	span.TraceState().FromRaw(sb.String())
+	return sampleRate, upstream
+}
+
+// thresholdToSampleRate returns the sample rate of an OpenTelemetry sampling threshold, rounded to the nearest
+// whole rate.
+func thresholdToSampleRate(threshold sampling.Threshold) int {
+	rate := math.Round(threshold.AdjustedCount())
+	if rate < 1 || rate > math.MaxInt32 {
+		return 1
+	}
+	return int(rate)
+}
@@ -0,0 +1,108 @@
+package dynamicsamplingprocessor
+
+import (
+	"testing"
+
+	"github.com/open-telemetry/opentelemetry-collector-contrib/pkg/sampling"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+func newProbabilitySpan(traceState string) ptrace.Span {
+	span := ptrace.NewSpan()
+	span.SetTraceID(pcommon.TraceID([16]byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 0x80}))
+	span.TraceState().FromRaw(traceState)
+	return span
+}
+
+func TestSampleRateToThreshold(t *testing.T) {
+	assert.Equal(t, sampling.AlwaysSampleThreshold, sampleRateToThreshold(1))
+	assert.Equal(t, "8", sampleRateToThreshold(2).TValue())
+	assert.Equal(t, "c", sampleRateToThreshold(4).TValue())
+}
+
+func TestThresholdToSampleRate(t *testing.T) {
+	assert.Equal(t, 1, thresholdToSampleRate(sampling.AlwaysSampleThreshold))
+	assert.Equal(t, 3, thresholdToSampleRate(sampleRateToThreshold(3)))
+	assert.Equal(t, 1, thresholdToSampleRate(sampling.NeverSampleThreshold))
+}
+
+func TestSpanRandomness(t *testing.T) {
+	fromTraceID := newProbabilitySpan("")
+	assert.Equal(t, "80000000000000", spanRandomness(fromTraceID).RValue())
+
+	fromRValue := newProbabilitySpan("ot=rv:0123456789abcd")
+	assert.Equal(t, "0123456789abcd", spanRandomness(fromRValue).RValue())
+
+	invalid := newProbabilitySpan("ot=rv:xyz")
+	assert.Equal(t, "80000000000000", spanRandomness(invalid).RValue())
+}
+
+func TestProbabilityDecision(t *testing.T) {
+	low := newProbabilitySpan("ot=rv:10000000000000")
+	high := newProbabilitySpan("ot=rv:f0000000000000")
+
+	assert.False(t, probabilityDecision(decision{keep: true, sampleRate: 4}, low).keep)
+	assert.True(t, probabilityDecision(decision{keep: false, sampleRate: 4}, high).keep)
+	assert.True(t, probabilityDecision(decision{keep: false, sampleRate: 1}, low).keep)
+	assert.False(t, probabilityDecision(decision{keep: false}, high).keep)
+}
+
+func TestUpdateTraceStateThreshold(t *testing.T) {
+	tests := []struct {
+		name         string
+		traceState   string
+		sampleRate   int
+		expected     string
+		expectedRate int
+		upstream     bool
+	}{
+		{
+			name:         "empty tracestate",
+			sampleRate:   4,
+			expected:     "ot=th:c",
+			expectedRate: 4,
+		},
+		{
+			name:         "other vendors are kept",
+			traceState:   "vendor=value,ot=rv:f0000000000000",
+			sampleRate:   2,
+			expected:     "ot=rv:f0000000000000;th:8,vendor=value",
+			expectedRate: 2,
+		},
+		{
+			name:         "lower upstream probability is kept",
+			traceState:   "ot=th:e;rv:f0000000000000",
+			sampleRate:   4,
+			expected:     "ot=th:e;rv:f0000000000000",
+			expectedRate: 8,
+			upstream:     true,
+		},
+		{
+			name:         "higher upstream probability is replaced",
+			traceState:   "ot=th:8;rv:f0000000000000",
+			sampleRate:   4,
+			expected:     "ot=rv:f0000000000000;th:c",
+			expectedRate: 4,
+			upstream:     true,
+		},
+		{
+			name:         "inconsistent upstream threshold is replaced",
+			traceState:   "ot=th:e;rv:d0000000000000",
+			sampleRate:   4,
+			expected:     "ot=rv:d0000000000000;th:c",
+			expectedRate: 4,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			span := newProbabilitySpan(tt.traceState)
+			rate, upstream := updateTraceStateThreshold(span, tt.sampleRate)
+			assert.Equal(t, tt.expectedRate, rate)
+			assert.Equal(t, tt.upstream, upstream)
+			assert.Equal(t, tt.expected, span.TraceState().AsRaw())
+		})
+	}
+}
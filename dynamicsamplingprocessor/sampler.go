@@ -0,0 +1,132 @@
+package dynamicsamplingprocessor
+
+import (
+	"fmt"
+
+	dynsampler "github.com/honeycombio/dynsampler-go"
+)
+
+// getSampler builds and starts the dynsampler-go sampler selected by the config.
+func getSampler(cfg *SamplerConfig) (dynsampler.Sampler, error) {
+	return startSampler(cfg, nil)
+}
+
+// startSampler builds the dynsampler-go sampler selected by the config, restores the given state if there is one
+// and starts the sampler.
+func startSampler(cfg *SamplerConfig, state []byte) (dynsampler.Sampler, error) {
+	sampler, err := newSampler(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(state) > 0 {
+		if err := loadSamplerState(sampler, state); err != nil {
+			return nil, fmt.Errorf("failed to load %s state: %w", cfg.Sampler, err)
+		}
+	}
+
+	if err := sampler.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start %s: %w", cfg.Sampler, err)
+	}
+	return sampler, nil
+}
+
+// replaceSampler starts a sampler for cfg with the state of sampler, leaving sampler running so that it can be
+// stopped once nothing uses it.
+func replaceSampler(sampler dynsampler.Sampler, cfg *SamplerConfig) (dynsampler.Sampler, error) {
+	state, err := sampler.SaveState()
+	if err != nil {
+		return nil, fmt.Errorf("failed to save %s state: %w", cfg.Sampler, err)
+	}
+	return startSampler(cfg, state)
+}
+
+// newSampler builds the dynsampler-go sampler selected by the config without starting it. When the config has more
+// than one shard, the sampler is a shardedSampler.
+func newSampler(cfg *SamplerConfig) (dynsampler.Sampler, error) {
+	if cfg.Shards > 1 {
+		return newShardedSampler(cfg)
+	}
+	return newShardSampler(cfg)
+}
+
+// newShardSampler builds a single dynsampler-go sampler selected by the config, ignoring the number of shards.
+func newShardSampler(cfg *SamplerConfig) (dynsampler.Sampler, error) {
+	var sampler dynsampler.Sampler
+	switch cfg.Sampler {
+	case EMADynamicSampler:
+		sampler = &dynsampler.EMASampleRate{
+			GoalSampleRate:             cfg.GoalSampleRate,
+			AdjustmentIntervalDuration: cfg.AdjustmentInterval,
+			Weight:                     cfg.Weight,
+			AgeOutValue:                cfg.AgeOutValue,
+			BurstMultiple:              cfg.BurstMultiple,
+			BurstDetectionDelay:        cfg.BurstDetectionDelay,
+			MaxKeys:                    cfg.MaxKeys,
+		}
+	case EMAThroughputSampler:
+		sampler = &dynsampler.EMAThroughput{
+			GoalThroughputPerSec: cfg.GoalThroughputPerSecond,
+			AdjustmentInterval:   cfg.AdjustmentInterval,
+			Weight:               cfg.Weight,
+			AgeOutValue:          cfg.AgeOutValue,
+			BurstMultiple:        cfg.BurstMultiple,
+			BurstDetectionDelay:  cfg.BurstDetectionDelay,
+			MaxKeys:              cfg.MaxKeys,
+		}
+	case AvgSampleRateSampler:
+		sampler = &dynsampler.AvgSampleRate{
+			GoalSampleRate:         cfg.AvgSampleRate.GoalSampleRate,
+			ClearFrequencyDuration: cfg.AvgSampleRate.ClearFrequency,
+			MaxKeys:                cfg.AvgSampleRate.MaxKeys,
+		}
+	case AvgSampleWithMinSampler:
+		sampler = &dynsampler.AvgSampleWithMin{
+			GoalSampleRate:         cfg.AvgSampleWithMin.GoalSampleRate,
+			MinEventsPerSec:        cfg.AvgSampleWithMin.MinEventsPerSecond,
+			ClearFrequencyDuration: cfg.AvgSampleWithMin.ClearFrequency,
+			MaxKeys:                cfg.AvgSampleWithMin.MaxKeys,
+		}
+	case TotalThroughputSampler:
+		sampler = &dynsampler.TotalThroughput{
+			GoalThroughputPerSec:   cfg.TotalThroughput.GoalThroughputPerSecond,
+			ClearFrequencyDuration: cfg.TotalThroughput.ClearFrequency,
+			MaxKeys:                cfg.TotalThroughput.MaxKeys,
+		}
+	case PerKeyThroughputSampler:
+		sampler = &dynsampler.PerKeyThroughput{
+			PerKeyThroughputPerSec: cfg.PerKeyThroughput.PerKeyThroughputPerSecond,
+			ClearFrequencyDuration: cfg.PerKeyThroughput.ClearFrequency,
+			MaxKeys:                cfg.PerKeyThroughput.MaxKeys,
+		}
+	case OnlyOnceSampler:
+		sampler = &dynsampler.OnlyOnce{
+			ClearFrequencyDuration: cfg.OnlyOnce.ClearFrequency,
+		}
+	case StaticSampler:
+		sampler = &dynsampler.Static{
+			Rates:   cfg.Static.Rates,
+			Default: cfg.Static.Default,
+		}
+	case WindowedThroughputSampler:
+		sampler = &dynsampler.WindowedThroughput{
+			GoalThroughputPerSec:      cfg.WindowedThroughput.GoalThroughputPerSecond,
+			UpdateFrequencyDuration:   cfg.WindowedThroughput.UpdateFrequency,
+			LookbackFrequencyDuration: cfg.WindowedThroughput.LookbackFrequency,
+			MaxKeys:                   cfg.WindowedThroughput.MaxKeys,
+		}
+	default:
+		return nil, fmt.Errorf("unknown sampler %q", cfg.Sampler)
+	}
+	return sampler, nil
+}
+
+// getSampleRate returns the sample rate for the given key, counting the record as count events. Some samplers, such
+// as WindowedThroughput, return 0 for keys they have not computed a rate for yet, so rates below 1 are treated as 1.
+func getSampleRate(sampler dynsampler.Sampler, key string, count int) int {
+	sampleRate := sampler.GetSampleRateMulti(key, count)
+	if sampleRate < 1 {
+		return 1
+	}
+	return sampleRate
+}
@@ -0,0 +1,75 @@
+package dynamicsamplingprocessor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	dynsampler "github.com/honeycombio/dynsampler-go"
+)
+
+func TestGetSampler(t *testing.T) {
+	tests := []struct {
+		sampler  string
+		expected dynsampler.Sampler
+	}{
+		{sampler: EMADynamicSampler, expected: &dynsampler.EMASampleRate{}},
+		{sampler: EMAThroughputSampler, expected: &dynsampler.EMAThroughput{}},
+		{sampler: AvgSampleRateSampler, expected: &dynsampler.AvgSampleRate{}},
+		{sampler: AvgSampleWithMinSampler, expected: &dynsampler.AvgSampleWithMin{}},
+		{sampler: TotalThroughputSampler, expected: &dynsampler.TotalThroughput{}},
+		{sampler: PerKeyThroughputSampler, expected: &dynsampler.PerKeyThroughput{}},
+		{sampler: OnlyOnceSampler, expected: &dynsampler.OnlyOnce{}},
+		{sampler: StaticSampler, expected: &dynsampler.Static{}},
+		{sampler: WindowedThroughputSampler, expected: &dynsampler.WindowedThroughput{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.sampler, func(t *testing.T) {
+			cfg := createDefaultConfig().(*Config)
+			cfg.Sampler = tt.sampler
+
+			sampler, err := getSampler(&cfg.SamplerConfig)
+			require.NoError(t, err)
+			assert.IsType(t, tt.expected, sampler)
+			assert.Positive(t, getSampleRate(sampler, "key", 1))
+			require.NoError(t, sampler.Stop())
+		})
+	}
+}
+
+func TestGetSamplerUnknown(t *testing.T) {
+	cfg := createDefaultConfig().(*Config)
+	cfg.Sampler = "NotASampler"
+
+	_, err := getSampler(&cfg.SamplerConfig)
+	assert.EqualError(t, err, `unknown sampler "NotASampler"`)
+}
+
+func TestGetSamplerEMATuning(t *testing.T) {
+	cfg := createDefaultConfig().(*Config)
+	cfg.Sampler = EMAThroughputSampler
+	cfg.GoalThroughputPerSecond = 100
+	cfg.AdjustmentInterval = 5 * time.Second
+	cfg.Weight = 0.2
+	cfg.AgeOutValue = 0.1
+	cfg.BurstMultiple = 3
+	cfg.BurstDetectionDelay = 1
+	cfg.MaxKeys = 1000
+
+	sampler, err := getSampler(&cfg.SamplerConfig)
+	require.NoError(t, err)
+	defer sampler.Stop()
+
+	ema, ok := sampler.(*dynsampler.EMAThroughput)
+	require.True(t, ok)
+	assert.Equal(t, 100, ema.GoalThroughputPerSec)
+	assert.Equal(t, 5*time.Second, ema.AdjustmentInterval)
+	assert.Equal(t, 0.2, ema.Weight)
+	assert.Equal(t, 0.1, ema.AgeOutValue)
+	assert.Equal(t, 3.0, ema.BurstMultiple)
+	assert.Equal(t, uint(1), ema.BurstDetectionDelay)
+	assert.Equal(t, 1000, ema.MaxKeys)
+}
@@ -0,0 +1,40 @@
+package dynamicsamplingprocessor
+
+import (
+	"math"
+	"strconv"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+)
+
+// defaultSampleRateAttribute is used when sample_rate_attribute is not set.
+const defaultSampleRateAttribute = "SampleRate"
+
+// upstreamSampleRate returns the sample rate a record was already sampled at before reaching this processor,
+// read from the named attribute. Records without a valid rate in the attribute are treated as unsampled and get
+// a rate of 1.
+func upstreamSampleRate(attrs pcommon.Map, name string) int {
+	val, ok := attrs.Get(name)
+	if !ok {
+		return 1
+	}
+
+	var rate int64
+	switch val.Type() {
+	case pcommon.ValueTypeInt:
+		rate = val.Int()
+	case pcommon.ValueTypeDouble:
+		rate = int64(math.Round(val.Double()))
+	case pcommon.ValueTypeStr:
+		parsed, err := strconv.ParseInt(val.Str(), 10, 64)
+		if err != nil {
+			return 1
+		}
+		rate = parsed
+	}
+
+	if rate < 1 || rate > math.MaxInt32 {
+		return 1
+	}
+	return int(rate)
+}
@@ -0,0 +1,70 @@
+package dynamicsamplingprocessor
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/consumer/consumertest"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/processor/processortest"
+)
+
+func TestUpstreamSampleRate(t *testing.T) {
+	tests := []struct {
+		name     string
+		set      func(attrs pcommon.Map)
+		expected int
+	}{
+		{name: "missing", set: func(pcommon.Map) {}, expected: 1},
+		{name: "int", set: func(attrs pcommon.Map) { attrs.PutInt("SampleRate", 20) }, expected: 20},
+		{name: "double", set: func(attrs pcommon.Map) { attrs.PutDouble("SampleRate", 4.6) }, expected: 5},
+		{name: "string", set: func(attrs pcommon.Map) { attrs.PutStr("SampleRate", "8") }, expected: 8},
+		{name: "invalid string", set: func(attrs pcommon.Map) { attrs.PutStr("SampleRate", "often") }, expected: 1},
+		{name: "zero", set: func(attrs pcommon.Map) { attrs.PutInt("SampleRate", 0) }, expected: 1},
+		{name: "negative", set: func(attrs pcommon.Map) { attrs.PutInt("SampleRate", -3) }, expected: 1},
+		{name: "bool", set: func(attrs pcommon.Map) { attrs.PutBool("SampleRate", true) }, expected: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attrs := pcommon.NewMap()
+			tt.set(attrs)
+			assert.Equal(t, tt.expected, upstreamSampleRate(attrs, "SampleRate"))
+		})
+	}
+}
+
+func TestLogsProcessorMultipliesUpstreamSampleRate(t *testing.T) {
+	cfg := createDefaultConfig().(*Config)
+	cfg.Sampler = StaticSampler
+	cfg.Static = StaticConfig{Default: 1}
+	cfg.SampleRateAttribute = "sampleRate"
+
+	sink := new(consumertest.LogsSink)
+	lp, err := NewFactory().CreateLogs(context.Background(), processortest.NewNopSettings(typ), cfg, sink)
+	require.NoError(t, err)
+
+	logs := plog.NewLogs()
+	records := logs.ResourceLogs().AppendEmpty().ScopeLogs().AppendEmpty().LogRecords()
+	records.AppendEmpty().Attributes().PutInt("sampleRate", 7)
+	records.AppendEmpty()
+
+	require.NoError(t, lp.ConsumeLogs(context.Background(), logs))
+	require.NoError(t, lp.Shutdown(context.Background()))
+
+	require.Len(t, sink.AllLogs(), 1)
+	got := sink.AllLogs()[0].ResourceLogs().At(0).ScopeLogs().At(0).LogRecords()
+	require.Equal(t, 2, got.Len())
+
+	rate, ok := got.At(0).Attributes().Get("sampleRate")
+	require.True(t, ok)
+	assert.Equal(t, int64(7), rate.Int())
+	rate, ok = got.At(1).Attributes().Get("sampleRate")
+	require.True(t, ok)
+	assert.Equal(t, int64(1), rate.Int())
+	_, ok = got.At(0).Attributes().Get("SampleRate")
+	assert.False(t, ok)
+}
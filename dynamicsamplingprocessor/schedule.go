@@ -0,0 +1,127 @@
+package dynamicsamplingprocessor
+
+import (
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// schedule is a parsed ScheduleConfig.
+type schedule struct {
+	name     string
+	cron     *cronSchedule
+	duration time.Duration
+	location *time.Location
+	goals    Goals
+}
+
+// active returns whether a window of the schedule is open at now.
+func (s *schedule) active(now time.Time) bool {
+	_, ok := s.cron.lastMatch(now.In(s.location), s.duration)
+	return ok
+}
+
+// scheduleController overrides the goal of the default sampler with the goal of the first schedule whose window
+// is open, and restores the configured goal when no window is open. Windows open and close on minute boundaries, so
+// the open windows are checked at the start of every minute.
+type scheduleController[K any] struct {
+	decider   *decider[K]
+	schedules []schedule
+	base      Goals
+	logger    *zap.Logger
+
+	// current is the index of the schedule whose goal is applied, or -1 for the configured goal.
+	current int
+
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+// newScheduleController returns a controller for the schedules of cfg, which must be valid.
+func newScheduleController[K any](d *decider[K], cfg *Config, logger *zap.Logger) *scheduleController[K] {
+	c := &scheduleController[K]{
+		decider: d,
+		base:    cfg.SamplerConfig.goals(),
+		logger:  logger,
+		current: -1,
+	}
+	for _, sc := range cfg.Schedules {
+		cron, _ := parseCron(sc.Cron)
+		location, _ := time.LoadLocation(sc.TimeZone)
+		c.schedules = append(c.schedules, schedule{
+			name:     sc.Name,
+			cron:     cron,
+			duration: sc.Duration,
+			location: location,
+			goals:    Goals{GoalSampleRate: sc.GoalSampleRate, GoalThroughputPerSecond: sc.GoalThroughputPerSecond},
+		})
+	}
+	return c
+}
+
+// start applies the goal of the schedule whose window is open, if any, and starts following the schedules. It
+// does nothing when no schedule is configured.
+func (c *scheduleController[K]) start() {
+	if len(c.schedules) == 0 {
+		return
+	}
+	c.update(time.Now())
+
+	c.done = make(chan struct{})
+	c.wg.Add(1)
+	go c.updateLoop()
+}
+
+// shutdown stops following the schedules.
+func (c *scheduleController[K]) shutdown() {
+	if c.done == nil {
+		return
+	}
+	close(c.done)
+	c.wg.Wait()
+}
+
+func (c *scheduleController[K]) updateLoop() {
+	defer c.wg.Done()
+
+	for {
+		next := time.Now().Truncate(time.Minute).Add(time.Minute)
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-timer.C:
+			c.update(next)
+		case <-c.done:
+			timer.Stop()
+			return
+		}
+	}
+}
+
+// update applies the goal of the first schedule whose window is open at now, or the configured goal if there is
+// none, when it differs from the goal applied.
+func (c *scheduleController[K]) update(now time.Time) {
+	current := -1
+	for i := range c.schedules {
+		if c.schedules[i].active(now) {
+			current = i
+			break
+		}
+	}
+	if current == c.current {
+		return
+	}
+
+	goals := c.base
+	name := ""
+	if current >= 0 {
+		goals = c.schedules[current].goals
+		name = c.schedules[current].name
+	}
+	if err := c.decider.setGoals(map[string]Goals{defaultSamplerName: goals}); err != nil {
+		c.logger.Warn("failed to apply scheduled dynamic sampler goals", zap.String("schedule", name), zap.Error(err))
+		return
+	}
+	c.logger.Info("applied scheduled dynamic sampler goals", zap.String("schedule", name))
+	c.current = current
+}
@@ -0,0 +1,88 @@
+package dynamicsamplingprocessor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestScheduleControllerSwitchesGoals(t *testing.T) {
+	cfg := createDefaultConfig().(*Config)
+	cfg.KeyFields = []string{"key1"}
+	cfg.GoalSampleRate = 10
+	cfg.Schedules = []ScheduleConfig{
+		{Name: "incident review", Cron: "0 12 * * *", Duration: time.Hour, TimeZone: "America/New_York", GoalSampleRate: 2},
+		{Name: "business hours", Cron: "0 9 * * mon-fri", Duration: 9 * time.Hour, TimeZone: "America/New_York", GoalSampleRate: 50},
+	}
+	require.NoError(t, cfg.Validate())
+
+	c := newScheduleController(newTestLogDecider(t, cfg), cfg, zap.NewNop())
+	newYork, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+
+	tests := []struct {
+		now      time.Time
+		expected int
+	}{
+		// Monday
+		{now: time.Date(2026, time.October, 19, 8, 59, 0, 0, newYork), expected: 10},
+		{now: time.Date(2026, time.October, 19, 9, 0, 0, 0, newYork), expected: 50},
+		{now: time.Date(2026, time.October, 19, 12, 30, 0, 0, newYork), expected: 2},
+		{now: time.Date(2026, time.October, 19, 13, 0, 0, 0, newYork), expected: 50},
+		{now: time.Date(2026, time.October, 19, 17, 59, 0, 0, newYork), expected: 50},
+		{now: time.Date(2026, time.October, 19, 18, 0, 0, 0, newYork), expected: 10},
+		// Saturday, given in UTC
+		{now: time.Date(2026, time.October, 24, 16, 0, 0, 0, time.UTC), expected: 2},
+		{now: time.Date(2026, time.October, 24, 17, 0, 0, 0, time.UTC), expected: 10},
+	}
+	for _, tt := range tests {
+		c.update(tt.now)
+		assert.Equal(t, tt.expected, c.decider.sampler.cfg.GoalSampleRate, tt.now)
+	}
+}
+
+func TestScheduleControllerThroughputGoal(t *testing.T) {
+	cfg := createDefaultConfig().(*Config)
+	cfg.Sampler = EMAThroughputSampler
+	cfg.KeyFields = []string{"key1"}
+	cfg.GoalThroughputPerSecond = 100
+	cfg.Schedules = []ScheduleConfig{
+		{Name: "night", Cron: "0 22 * * *", Duration: 8 * time.Hour, GoalThroughputPerSecond: 1000},
+	}
+	require.NoError(t, cfg.Validate())
+
+	c := newScheduleController(newTestLogDecider(t, cfg), cfg, zap.NewNop())
+
+	// the window opened the day before
+	c.update(time.Date(2026, time.October, 18, 5, 59, 0, 0, time.UTC))
+	assert.Equal(t, 1000, c.decider.sampler.cfg.GoalThroughputPerSecond)
+	assert.Equal(t, 0, c.current)
+
+	c.update(time.Date(2026, time.October, 18, 6, 0, 0, 0, time.UTC))
+	assert.Equal(t, 100, c.decider.sampler.cfg.GoalThroughputPerSecond)
+	assert.Equal(t, -1, c.current)
+}
+
+func TestScheduleControllerFractionalGoal(t *testing.T) {
+	cfg := createDefaultConfig().(*Config)
+	cfg.Sampler = WindowedThroughputSampler
+	cfg.KeyFields = []string{"key1"}
+	cfg.WindowedThroughput = WindowedThroughputConfig{GoalThroughputPerSecond: 2}
+	cfg.Schedules = []ScheduleConfig{
+		{Name: "night", Cron: "0 22 * * *", Duration: 8 * time.Hour, GoalThroughputPerSecond: 0.5},
+	}
+	require.NoError(t, cfg.Validate())
+
+	c := newScheduleController(newTestLogDecider(t, cfg), cfg, zap.NewNop())
+
+	c.update(time.Date(2026, time.October, 18, 23, 0, 0, 0, time.UTC))
+	assert.Equal(t, 0.5, c.decider.sampler.cfg.WindowedThroughput.GoalThroughputPerSecond)
+	assert.Equal(t, 0, c.current)
+
+	c.update(time.Date(2026, time.October, 19, 6, 0, 0, 0, time.UTC))
+	assert.Equal(t, 2.0, c.decider.sampler.cfg.WindowedThroughput.GoalThroughputPerSecond)
+	assert.Equal(t, -1, c.current)
+}
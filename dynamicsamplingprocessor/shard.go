@@ -0,0 +1,217 @@
+package dynamicsamplingprocessor
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	dynsampler "github.com/honeycombio/dynsampler-go"
+)
+
+// shardedSampler spreads keys over several dynsampler-go samplers by a hash of the key. Each sampler has its own
+// lock, so records with keys in different shards are sampled in parallel. A key is always sampled by the same shard,
+// also after a restart, so saved state stays with its key.
+type shardedSampler struct {
+	shards []dynsampler.Sampler
+}
+
+// newShardedSampler builds cfg.Shards samplers, each with the shard config of cfg, without starting them.
+func newShardedSampler(cfg *SamplerConfig) (*shardedSampler, error) {
+	shardCfg := cfg.shardConfig(cfg.Shards)
+	s := &shardedSampler{shards: make([]dynsampler.Sampler, cfg.Shards)}
+	for i := range s.shards {
+		sampler, err := newShardSampler(&shardCfg)
+		if err != nil {
+			return nil, err
+		}
+		s.shards[i] = sampler
+	}
+	return s, nil
+}
+
+// Start starts the samplers of all shards. The samplers already started are stopped if one fails to start.
+func (s *shardedSampler) Start() error {
+	for i, sampler := range s.shards {
+		if err := sampler.Start(); err != nil {
+			for _, started := range s.shards[:i] {
+				_ = started.Stop()
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// Stop stops the samplers of all shards.
+func (s *shardedSampler) Stop() error {
+	var errs error
+	for _, sampler := range s.shards {
+		errs = errors.Join(errs, sampler.Stop())
+	}
+	return errs
+}
+
+// GetSampleRate returns the sample rate computed for key by the shard of the key, counting one record.
+func (s *shardedSampler) GetSampleRate(key string) int {
+	return s.GetSampleRateMulti(key, 1)
+}
+
+// GetSampleRateMulti returns the sample rate computed for key by the shard of the key.
+func (s *shardedSampler) GetSampleRateMulti(key string, count int) int {
+	return s.shards[keyShard(key, len(s.shards))].GetSampleRateMulti(key, count)
+}
+
+// SaveState returns the states of the shards as a JSON array, or nil if none of the shards have state to save.
+func (s *shardedSampler) SaveState() ([]byte, error) {
+	states := make([]json.RawMessage, len(s.shards))
+	empty := true
+	for i, sampler := range s.shards {
+		state, err := sampler.SaveState()
+		if err != nil {
+			return nil, err
+		}
+		if len(state) > 0 {
+			states[i] = state
+			empty = false
+		}
+	}
+	if empty {
+		return nil, nil
+	}
+	return json.Marshal(states)
+}
+
+// LoadState restores the states returned by SaveState. State saved with a different number of shards, or by a
+// sampler without shards, is re-sharded first, so that each key's state moves to the shard the key now hashes to.
+func (s *shardedSampler) LoadState(state []byte) error {
+	states, err := reshardState(state, len(s.shards))
+	if err != nil {
+		return err
+	}
+	for i, sampler := range s.shards {
+		if len(states[i]) == 0 || string(states[i]) == "null" {
+			continue
+		}
+		if err := sampler.LoadState(states[i]); err != nil {
+			return fmt.Errorf("shard %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// GetMetrics returns the metrics of all shards added up.
+func (s *shardedSampler) GetMetrics(prefix string) map[string]int64 {
+	metrics := make(map[string]int64)
+	for _, sampler := range s.shards {
+		for name, value := range sampler.GetMetrics(prefix) {
+			metrics[name] += value
+		}
+	}
+	return metrics
+}
+
+// loadSamplerState restores state into sampler. State saved by a shardedSampler is merged into one state first when
+// sampler has no shards.
+func loadSamplerState(sampler dynsampler.Sampler, state []byte) error {
+	if _, ok := sampler.(*shardedSampler); ok || !isShardedState(state) {
+		return sampler.LoadState(state)
+	}
+	states, err := reshardState(state, 1)
+	if err != nil {
+		return err
+	}
+	if len(states[0]) == 0 {
+		return nil
+	}
+	return sampler.LoadState(states[0])
+}
+
+// isShardedState reports whether state was saved by a shardedSampler, which saves a JSON array rather than the
+// JSON object saved by dynsampler-go samplers.
+func isShardedState(state []byte) bool {
+	trimmed := bytes.TrimLeft(state, " \t\r\n")
+	return len(trimmed) > 0 && trimmed[0] == '['
+}
+
+// reshardState splits the state saved by a sampler with any number of shards into the states of the given number
+// of shards. The dynsampler-go samplers save JSON objects whose object fields map keys to what was learned about
+// them, so each key's entries go to the shard the key hashes to. Other fields are copied to every shard. State saved
+// with the given number of shards is returned as it is.
+func reshardState(state []byte, shards int) ([]json.RawMessage, error) {
+	var saved []json.RawMessage
+	if isShardedState(state) {
+		if err := json.Unmarshal(state, &saved); err != nil {
+			return nil, err
+		}
+	} else {
+		saved = []json.RawMessage{state}
+	}
+	if len(saved) == shards {
+		return saved, nil
+	}
+
+	fields := make([]map[string]any, shards)
+	for _, shardState := range saved {
+		if len(shardState) == 0 || string(shardState) == "null" {
+			continue
+		}
+		var object map[string]json.RawMessage
+		if err := json.Unmarshal(shardState, &object); err != nil {
+			return nil, err
+		}
+		for name, value := range object {
+			var entries map[string]json.RawMessage
+			if err := json.Unmarshal(value, &entries); err != nil || entries == nil {
+				for i := range fields {
+					if fields[i] == nil {
+						fields[i] = make(map[string]any)
+					}
+					if _, ok := fields[i][name]; !ok {
+						fields[i][name] = value
+					}
+				}
+				continue
+			}
+			for i := range fields {
+				if fields[i] == nil {
+					fields[i] = make(map[string]any)
+				}
+				if _, ok := fields[i][name].(map[string]json.RawMessage); !ok {
+					fields[i][name] = make(map[string]json.RawMessage)
+				}
+			}
+			for key, entry := range entries {
+				fields[keyShard(key, shards)][name].(map[string]json.RawMessage)[key] = entry
+			}
+		}
+	}
+
+	states := make([]json.RawMessage, shards)
+	for i, shardFields := range fields {
+		if shardFields == nil {
+			continue
+		}
+		state, err := json.Marshal(shardFields)
+		if err != nil {
+			return nil, err
+		}
+		states[i] = state
+	}
+	return states, nil
+}
+
+// keyShard returns the shard of key out of the given number of shards, using the 32-bit FNV-1a hash of the key. The
+// hash is computed inline rather than with hash/fnv so that the key does not escape to the heap.
+func keyShard(key string, shards int) int {
+	const (
+		offset32 = 2166136261
+		prime32  = 16777619
+	)
+	hash := uint32(offset32)
+	for i := 0; i < len(key); i++ {
+		hash ^= uint32(key[i])
+		hash *= prime32
+	}
+	return int(hash % uint32(shards))
+}
@@ -0,0 +1,170 @@
+package dynamicsamplingprocessor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/open-telemetry/opentelemetry-collector-contrib/pkg/ottl/contexts/ottllog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	dynsampler "github.com/honeycombio/dynsampler-go"
+)
+
+func TestShardedSampler(t *testing.T) {
+	cfg := createDefaultConfig().(*Config)
+	cfg.Sampler = EMAThroughputSampler
+	cfg.GoalThroughputPerSecond = 100
+	cfg.MaxKeys = 10
+	cfg.Shards = 4
+
+	sampler, err := getSampler(&cfg.SamplerConfig)
+	require.NoError(t, err)
+	defer sampler.Stop()
+
+	sharded, ok := sampler.(*shardedSampler)
+	require.True(t, ok)
+	require.Len(t, sharded.shards, 4)
+	for _, shard := range sharded.shards {
+		ema, ok := shard.(*dynsampler.EMAThroughput)
+		require.True(t, ok)
+		assert.Equal(t, 25, ema.GoalThroughputPerSec)
+		assert.Equal(t, 3, ema.MaxKeys)
+	}
+
+	for i := 0; i < 100; i++ {
+		assert.Positive(t, getSampleRate(sampler, fmt.Sprintf("key-%d", i), 1))
+	}
+	// each shard tracks up to a quarter of max_keys, rounded up
+	assert.Equal(t, int64(12), sampler.GetMetrics("")["keyspace_size"])
+}
+
+func TestShardConfig(t *testing.T) {
+	cfg := createDefaultConfig().(*Config)
+	cfg.Shards = 3
+	cfg.AvgSampleRate.GoalSampleRate = 10
+	cfg.AvgSampleWithMin.GoalSampleRate = 10
+	cfg.TotalThroughput.GoalThroughputPerSecond = 100
+	cfg.PerKeyThroughput.PerKeyThroughputPerSecond = 10
+	cfg.WindowedThroughput.GoalThroughputPerSecond = 90
+
+	shardCfg := cfg.shardConfig(3)
+	assert.Equal(t, 1, shardCfg.Shards)
+	assert.Equal(t, 10, shardCfg.GoalSampleRate)
+	assert.Equal(t, 10, shardCfg.AvgSampleRate.GoalSampleRate)
+	assert.Equal(t, 17, shardCfg.AvgSampleWithMin.MinEventsPerSecond)
+	assert.Equal(t, 34, shardCfg.TotalThroughput.GoalThroughputPerSecond)
+	assert.Equal(t, 10, shardCfg.PerKeyThroughput.PerKeyThroughputPerSecond)
+	assert.Equal(t, 30.0, shardCfg.WindowedThroughput.GoalThroughputPerSecond)
+	assert.Equal(t, 0, shardCfg.MaxKeys)
+}
+
+func TestShardedSamplerState(t *testing.T) {
+	cfg := createDefaultConfig().(*Config)
+	cfg.Sampler = AvgSampleRateSampler
+	cfg.AvgSampleRate.GoalSampleRate = 10
+
+	rates := make(map[string]int)
+	for i := 0; i < 20; i++ {
+		rates[fmt.Sprintf("key-%d", i)] = i + 1
+	}
+	unshardedState, err := json.Marshal(map[string]any{"saved_sample_rates": rates})
+	require.NoError(t, err)
+
+	// state saved without shards is split between the shards by the hash of each key
+	cfg.Shards = 4
+	sampler, err := startSampler(&cfg.SamplerConfig, unshardedState)
+	require.NoError(t, err)
+	state := mustSaveState(t, sampler)
+	require.NoError(t, sampler.Stop())
+	var shardStates []map[string]map[string]int
+	require.NoError(t, json.Unmarshal(state, &shardStates))
+	require.Len(t, shardStates, 4)
+	for i, shardState := range shardStates {
+		for key, rate := range shardState["saved_sample_rates"] {
+			assert.Equal(t, i, keyShard(key, 4), key)
+			assert.Equal(t, rates[key], rate, key)
+			assert.Equal(t, rate, sampler.(*shardedSampler).shards[i].GetSampleRate(key), key)
+		}
+	}
+	assert.Equal(t, rates, savedSampleRates(t, state))
+
+	restored, err := startSampler(&cfg.SamplerConfig, state)
+	require.NoError(t, err)
+	require.NoError(t, restored.Stop())
+	assert.JSONEq(t, string(state), string(mustSaveState(t, restored)))
+
+	// state is re-sharded when the number of shards changes, so every key keeps its saved sample rate
+	for _, shards := range []int{2, 1, 8} {
+		cfg.Shards = shards
+		resharded, err := startSampler(&cfg.SamplerConfig, state)
+		require.NoError(t, err, "%d shards", shards)
+		require.NoError(t, resharded.Stop())
+		assert.Equal(t, rates, savedSampleRates(t, mustSaveState(t, resharded)), "%d shards", shards)
+	}
+}
+
+// savedSampleRates returns the saved sample rates of every key in the state of an AvgSampleRate sampler, with or
+// without shards.
+func savedSampleRates(t *testing.T, state []byte) map[string]int {
+	states, err := reshardState(state, 1)
+	require.NoError(t, err)
+	var saved struct {
+		SavedSampleRates map[string]int `json:"saved_sample_rates"`
+	}
+	require.NoError(t, json.Unmarshal(states[0], &saved))
+	return saved.SavedSampleRates
+}
+
+func mustSaveState(t *testing.T, sampler dynsampler.Sampler) []byte {
+	state, err := sampler.SaveState()
+	require.NoError(t, err)
+	return state
+}
+
+func TestKeyShard(t *testing.T) {
+	counts := make([]int, 8)
+	for i := 0; i < 1000; i++ {
+		key := fmt.Sprintf("key-%d", i)
+		shard := keyShard(key, len(counts))
+		assert.Equal(t, shard, keyShard(key, len(counts)))
+		counts[shard]++
+	}
+	for shard, count := range counts {
+		assert.Positive(t, count, "shard %d", shard)
+	}
+	assert.Equal(t, 0, keyShard("key", 1))
+}
+
+// BenchmarkSampleRate measures the records per second that concurrent callers get through the default sampler for a
+// range of shard counts. Run it with -cpu to compare the number of cores, for example -cpu 1,4,16.
+func BenchmarkSampleRate(b *testing.B) {
+	lookups := benchmarkLookups(1000)
+	for _, shards := range []int{1, 2, 4, 8, 16, 32, 64} {
+		b.Run(fmt.Sprintf("shards=%d", shards), func(b *testing.B) {
+			cfg := createDefaultConfig().(*Config)
+			cfg.KeyFields = []string{"service.name", "http.response.status_code"}
+			cfg.Shards = shards
+			s, err := newKeyedSampler[ottllog.TransformContext](defaultSamplerName, &cfg.SamplerConfig, nil)
+			require.NoError(b, err)
+			require.NoError(b, s.start())
+			defer s.stop()
+
+			logger := zap.NewNop()
+			b.ReportAllocs()
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				var tCtx ottllog.TransformContext
+				i := 0
+				for pb.Next() {
+					s.sampleRate(context.Background(), tCtx, lookups[i%len(lookups)], 1, logger)
+					i++
+				}
+			})
+			b.ReportMetric(float64(b.N)/b.Elapsed().Seconds(), "records/s")
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package dynamicsamplingprocessor
+
+import (
+	"sync"
+
+	"go.opentelemetry.io/collector/component"
+)
+
+// sharedComponents holds one value per component ID, shared by the logs and traces processors created with that ID.
+// The value is created by the first processor that acquires it and forgotten once every processor holding it has
+// released it.
+type sharedComponents[T any] struct {
+	mu      sync.Mutex
+	entries map[component.ID]*sharedComponent[T]
+}
+
+type sharedComponent[T any] struct {
+	value T
+	refs  int
+}
+
+func newSharedComponents[T any]() *sharedComponents[T] {
+	return &sharedComponents[T]{entries: make(map[component.ID]*sharedComponent[T])}
+}
+
+// acquire returns the value for id, creating it with create if no processor holds it.
+func (s *sharedComponents[T]) acquire(id component.ID, create func() T) T {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.entries[id]
+	if !ok {
+		entry = &sharedComponent[T]{value: create()}
+		s.entries[id] = entry
+	}
+	entry.refs++
+	return entry.value
+}
+
+// release gives up one hold of the value for id.
+func (s *sharedComponents[T]) release(id component.ID) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.entries[id]
+	if !ok {
+		return
+	}
+	entry.refs--
+	if entry.refs <= 0 {
+		delete(s.entries, id)
+	}
+}
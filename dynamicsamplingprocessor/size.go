@@ -0,0 +1,24 @@
+package dynamicsamplingprocessor
+
+import (
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+// Proto marshalers are used to calculate the size of records when throughput_unit is bytes.
+var (
+	logsMarshaler   = plog.ProtoMarshaler{}
+	tracesMarshaler = ptrace.ProtoMarshaler{}
+)
+
+// logRecordSize returns the size of the log record in the OTLP protobuf encoding, and at least 1 so that every
+// record counts towards the sampler's traffic.
+func logRecordSize(lr plog.LogRecord) int {
+	return max(logsMarshaler.LogRecordSize(lr), 1)
+}
+
+// spanSize returns the size of the span in the OTLP protobuf encoding, and at least 1 so that every span counts
+// towards the sampler's traffic.
+func spanSize(span ptrace.Span) int {
+	return max(tracesMarshaler.SpanSize(span), 1)
+}
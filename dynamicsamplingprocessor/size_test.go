@@ -0,0 +1,75 @@
+package dynamicsamplingprocessor
+
+import (
+	"context"
+	"testing"
+
+	"github.com/open-telemetry/opentelemetry-collector-contrib/pkg/ottl/contexts/ottllog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+
+	dynsampler "github.com/honeycombio/dynsampler-go"
+
+	"github.com/honeycombio/opentelemetry-collector-configs/dynamicsamplingprocessor/internal/metadata"
+)
+
+func TestLogsProcessorCountsBytes(t *testing.T) {
+	telemetryBuilder, err := metadata.NewTelemetryBuilder(componenttest.NewNopTelemetrySettings())
+	require.NoError(t, err)
+
+	sampler := &recordingSampler{Static: dynsampler.Static{Default: 1}}
+	lsp := &logsProcessor{
+		decider: &decider[ottllog.TransformContext]{
+			sampler: &keyedSampler[ottllog.TransformContext]{
+				sampler:   sampler,
+				keyFields: []string{"key1"},
+			},
+		},
+		sampleRateAttribute: defaultSampleRateAttribute,
+		weighted:            true,
+		countBytes:          true,
+		telemetryBuilder:    telemetryBuilder,
+	}
+
+	logs := plog.NewLogs()
+	records := logs.ResourceLogs().AppendEmpty().ScopeLogs().AppendEmpty().LogRecords()
+	short := records.AppendEmpty()
+	short.Body().SetStr("ok")
+	long := records.AppendEmpty()
+	long.Body().SetStr(string(make([]byte, 40_000)))
+	long.Attributes().PutInt("SampleRate", 2)
+
+	// sizes are taken before the sample rate attribute is written
+	expected := []int{logRecordSize(short), 2 * logRecordSize(long)}
+
+	_, err = lsp.processLogs(context.Background(), logs)
+	require.NoError(t, err)
+	assert.Equal(t, expected, sampler.counts)
+	assert.Greater(t, sampler.counts[1], 80_000)
+}
+
+func TestTracesProcessorCountsBytes(t *testing.T) {
+	sampler := &recordingSampler{Static: dynsampler.Static{Default: 1}}
+	tsp := newTestTracesProcessor(t, sampler)
+	tsp.countBytes = true
+
+	td := ptrace.NewTraces()
+	ss := td.ResourceSpans().AppendEmpty().ScopeSpans().AppendEmpty()
+	traceID := pcommon.TraceID([16]byte{1})
+	appendSpan(ss, traceID, pcommon.SpanID([8]byte{1}), pcommon.NewSpanIDEmpty(), "root")
+	appendSpan(ss, traceID, pcommon.SpanID([8]byte{2}), pcommon.SpanID([8]byte{1}), "child")
+	appendSpan(ss, pcommon.TraceID([16]byte{2}), pcommon.SpanID([8]byte{3}), pcommon.NewSpanIDEmpty(), "other")
+
+	spans := ss.Spans()
+	expected := []int{
+		spanSize(spans.At(0)) + spanSize(spans.At(1)),
+		spanSize(spans.At(2)),
+	}
+
+	tsp.makeDecisions(context.Background(), td)
+	assert.Equal(t, expected, sampler.counts)
+}
@@ -0,0 +1,141 @@
+package dynamicsamplingprocessor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/extension/xextension/storage"
+	"go.uber.org/zap"
+)
+
+// defaultStateSnapshotInterval is used when state_snapshot_interval is not set.
+const defaultStateSnapshotInterval = time.Minute
+
+// stateStore persists the state of the samplers of a decider in a storage extension. State is restored when the
+// processor starts, and saved periodically and when the processor shuts down.
+type stateStore[K any] struct {
+	decider     *decider[K]
+	storageID   *component.ID
+	componentID component.ID
+	signal      string
+	interval    time.Duration
+	logger      *zap.Logger
+
+	client storage.Client
+	done   chan struct{}
+	wg     sync.WaitGroup
+}
+
+func newStateStore[K any](d *decider[K], cfg *Config, componentID component.ID, signal string, logger *zap.Logger) *stateStore[K] {
+	interval := cfg.StateSnapshotInterval
+	if interval == 0 {
+		interval = defaultStateSnapshotInterval
+	}
+	return &stateStore[K]{
+		decider:     d,
+		storageID:   cfg.StorageID,
+		componentID: componentID,
+		signal:      signal,
+		interval:    interval,
+		logger:      logger,
+	}
+}
+
+// start restores the saved sampler state and starts saving it periodically. It does nothing when no storage
+// extension is configured.
+func (s *stateStore[K]) start(ctx context.Context, host component.Host) error {
+	if s.storageID == nil {
+		return nil
+	}
+
+	ext, ok := host.GetExtensions()[*s.storageID]
+	if !ok {
+		return fmt.Errorf("storage extension %q not found", s.storageID)
+	}
+	storageExt, ok := ext.(storage.Extension)
+	if !ok {
+		return fmt.Errorf("extension %q is not a storage extension", s.storageID)
+	}
+	client, err := storageExt.GetClient(ctx, component.KindProcessor, s.componentID, s.signal)
+	if err != nil {
+		return fmt.Errorf("failed to get storage client: %w", err)
+	}
+	s.client = client
+
+	s.restore(ctx)
+
+	s.done = make(chan struct{})
+	s.wg.Add(1)
+	go s.snapshotLoop()
+	return nil
+}
+
+// shutdown stops the periodic snapshots, saves the sampler state a last time and closes the storage client.
+func (s *stateStore[K]) shutdown(ctx context.Context) error {
+	if s.client == nil {
+		return nil
+	}
+
+	close(s.done)
+	s.wg.Wait()
+
+	err := s.snapshot(ctx)
+	return errors.Join(err, s.client.Close(ctx))
+}
+
+func (s *stateStore[K]) snapshotLoop() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := s.snapshot(context.Background()); err != nil {
+				s.logger.Warn("failed to save dynamic sampler state", zap.Error(err))
+			}
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// snapshot saves the state of every sampler that has state, under the name of the sampler.
+func (s *stateStore[K]) snapshot(ctx context.Context) error {
+	var ops []*storage.Operation
+	for _, sampler := range s.decider.samplers {
+		state, err := sampler.saveState()
+		if err != nil {
+			return fmt.Errorf("failed to save state of sampler %q: %w", sampler.name, err)
+		}
+		if len(state) > 0 {
+			ops = append(ops, storage.SetOperation(sampler.name, state))
+		}
+	}
+	if len(ops) == 0 {
+		return nil
+	}
+	return s.client.Batch(ctx, ops...)
+}
+
+// restore loads the saved state of every sampler. Samplers without saved state, or whose state cannot be loaded,
+// start afresh.
+func (s *stateStore[K]) restore(ctx context.Context) {
+	for _, sampler := range s.decider.samplers {
+		state, err := s.client.Get(ctx, sampler.name)
+		if err != nil {
+			s.logger.Warn("failed to read dynamic sampler state", zap.String("sampler", sampler.name), zap.Error(err))
+			continue
+		}
+		if len(state) == 0 {
+			continue
+		}
+		if err := sampler.loadState(state); err != nil {
+			s.logger.Warn("failed to restore dynamic sampler state", zap.String("sampler", sampler.name), zap.Error(err))
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package dynamicsamplingprocessor
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/collector/consumer/consumertest"
+	"go.opentelemetry.io/collector/extension/xextension/storage"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/processor/processortest"
+)
+
+// memoryStorage is a storage extension that keeps its data in memory.
+type memoryStorage struct {
+	component.StartFunc
+	component.ShutdownFunc
+
+	mu   sync.Mutex
+	data map[string][]byte
+}
+
+func (m *memoryStorage) GetClient(context.Context, component.Kind, component.ID, string) (storage.Client, error) {
+	return &memoryClient{storage: m}, nil
+}
+
+type memoryClient struct {
+	storage *memoryStorage
+}
+
+func (c *memoryClient) Get(_ context.Context, key string) ([]byte, error) {
+	c.storage.mu.Lock()
+	defer c.storage.mu.Unlock()
+	return c.storage.data[key], nil
+}
+
+func (c *memoryClient) Set(_ context.Context, key string, value []byte) error {
+	c.storage.mu.Lock()
+	defer c.storage.mu.Unlock()
+	c.storage.data[key] = value
+	return nil
+}
+
+func (c *memoryClient) Delete(_ context.Context, key string) error {
+	c.storage.mu.Lock()
+	defer c.storage.mu.Unlock()
+	delete(c.storage.data, key)
+	return nil
+}
+
+func (c *memoryClient) Batch(ctx context.Context, ops ...*storage.Operation) error {
+	for _, op := range ops {
+		var err error
+		switch op.Type {
+		case storage.Get:
+			op.Value, err = c.Get(ctx, op.Key)
+		case storage.Set:
+			err = c.Set(ctx, op.Key, op.Value)
+		case storage.Delete:
+			err = c.Delete(ctx, op.Key)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *memoryClient) Close(context.Context) error {
+	return nil
+}
+
+type storageHost struct {
+	component.Host
+	extensions map[component.ID]component.Component
+}
+
+func (h storageHost) GetExtensions() map[component.ID]component.Component {
+	return h.extensions
+}
+
+func TestStateRestoredAndSaved(t *testing.T) {
+	storageID := component.MustNewID("file_storage")
+	ext := &memoryStorage{data: map[string][]byte{
+		"default": []byte(`{"saved_sample_rates":{"checkout":7},"moving_average":{"checkout":70}}`),
+	}}
+	host := storageHost{Host: componenttest.NewNopHost(), extensions: map[component.ID]component.Component{storageID: ext}}
+
+	cfg := createDefaultConfig().(*Config)
+	cfg.KeyFields = []string{"key1"}
+	cfg.StorageID = &storageID
+
+	sink := new(consumertest.LogsSink)
+	lp, err := NewFactory().CreateLogs(context.Background(), processortest.NewNopSettings(typ), cfg, sink)
+	require.NoError(t, err)
+	require.NoError(t, lp.Start(context.Background(), host))
+
+	logs := plog.NewLogs()
+	logs.ResourceLogs().AppendEmpty().ScopeLogs().AppendEmpty().LogRecords().AppendEmpty().Attributes().PutStr("key1", "checkout")
+	require.NoError(t, lp.ConsumeLogs(context.Background(), logs))
+
+	// the restored rate is used instead of the goal sample rate of a fresh sampler
+	for _, l := range sink.AllLogs() {
+		rate, ok := l.ResourceLogs().At(0).ScopeLogs().At(0).LogRecords().At(0).Attributes().Get("SampleRate")
+		require.True(t, ok)
+		assert.Equal(t, int64(7), rate.Int())
+	}
+
+	ext.data = map[string][]byte{}
+	require.NoError(t, lp.Shutdown(context.Background()))
+
+	var saved map[string]any
+	require.NoError(t, json.Unmarshal(ext.data["default"], &saved))
+	assert.Equal(t, map[string]any{"checkout": float64(7)}, saved["saved_sample_rates"])
+}
+
+func TestStateMissingStorageExtension(t *testing.T) {
+	storageID := component.MustNewID("file_storage")
+	cfg := createDefaultConfig().(*Config)
+	cfg.StorageID = &storageID
+
+	lp, err := NewFactory().CreateLogs(context.Background(), processortest.NewNopSettings(typ), cfg, consumertest.NewNop())
+	require.NoError(t, err)
+	assert.ErrorContains(t, lp.Start(context.Background(), componenttest.NewNopHost()), `storage extension "file_storage" not found`)
+	require.NoError(t, lp.Shutdown(context.Background()))
+}
+
+func TestPartitionedSamplerState(t *testing.T) {
+	cfg := SamplerConfig{
+		Sampler:        EMADynamicSampler,
+		KeyFields:      []string{"key1"},
+		GoalSampleRate: 10,
+		PartitionBy:    "service.name",
+	}
+	p := newPartitionedSampler(&cfg)
+	t.Cleanup(func() { require.NoError(t, p.stop()) })
+	require.NoError(t, p.loadState([]byte(`{"checkout":{"saved_sample_rates":{"a":3},"moving_average":{"a":30}}}`)))
+
+	checkout, err := p.get("checkout", time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, 3, checkout.GetSampleRate("a"))
+
+	payments, err := p.get("payments", time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, 10, payments.GetSampleRate("a"))
+
+	state, err := p.saveState()
+	require.NoError(t, err)
+	var saved map[string]map[string]any
+	require.NoError(t, json.Unmarshal(state, &saved))
+	assert.Equal(t, map[string]any{"a": float64(3)}, saved["checkout"]["saved_sample_rates"])
+	assert.Contains(t, saved, "payments")
+}
@@ -0,0 +1,180 @@
+package dynamicsamplingprocessor
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/collector/consumer"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.uber.org/zap"
+
+	"github.com/honeycombio/opentelemetry-collector-configs/dynamicsamplingprocessor/internal/metadata"
+)
+
+// defaultSummaryInterval is used when summaries interval is not set.
+const defaultSummaryInterval = time.Minute
+
+// Attributes of summary log records.
+const (
+	summaryAttribute                    = "sampler.summary"
+	summarySamplerAttribute             = "sampler.name"
+	summaryRuleAttribute                = "sampler.rule"
+	summaryKeyAttribute                 = dryRunKeyAttribute
+	summarySeenAttribute                = "sampler.seen_count"
+	summaryKeptAttribute                = "sampler.kept_count"
+	summaryDroppedAttribute             = "sampler.dropped_count"
+	summaryEffectiveSampleRateAttribute = "sampler.effective_sample_rate"
+)
+
+// summaryKey identifies the records counted together in a summary: those given the same key by the same sampler,
+// or handled by the same rule.
+type summaryKey struct {
+	sampler string
+	rule    string
+	key     string
+}
+
+type summaryCounts struct {
+	seen    int64
+	dropped int64
+}
+
+// summarizer counts the records seen and dropped for each key and periodically sends a summary log record per key
+// to the next consumer.
+type summarizer struct {
+	interval time.Duration
+	next     consumer.Logs
+	logger   *zap.Logger
+
+	mu     sync.Mutex
+	counts map[summaryKey]*summaryCounts
+
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+func newSummarizer(cfg *SummariesConfig, next consumer.Logs, logger *zap.Logger) *summarizer {
+	interval := cfg.Interval
+	if interval == 0 {
+		interval = defaultSummaryInterval
+	}
+	return &summarizer{
+		interval: interval,
+		next:     next,
+		logger:   logger,
+		counts:   make(map[summaryKey]*summaryCounts),
+	}
+}
+
+// record counts a record with the given decision. Keys first seen after maxTrackedKeys keys have been counted in an
+// interval are counted under the overflow key of their sampler or rule.
+func (s *summarizer) record(d decision) {
+	k := summaryKey{sampler: d.sampler, rule: d.rule, key: d.key}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	counts, ok := s.counts[k]
+	if !ok {
+		if len(s.counts) >= maxTrackedKeys {
+			k.key = overflowKey
+			counts, ok = s.counts[k]
+		}
+		if !ok {
+			counts = &summaryCounts{}
+			s.counts[k] = counts
+		}
+	}
+	counts.seen++
+	if !d.keep {
+		counts.dropped++
+	}
+}
+
+func (s *summarizer) start() {
+	s.done = make(chan struct{})
+	s.wg.Add(1)
+	go s.loop()
+}
+
+// shutdown stops the periodic summaries and sends the summaries of the current interval.
+func (s *summarizer) shutdown(ctx context.Context) error {
+	if s.done == nil {
+		return nil
+	}
+	close(s.done)
+	s.wg.Wait()
+	return s.flush(ctx, time.Now())
+}
+
+func (s *summarizer) loop() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case now := <-ticker.C:
+			if err := s.flush(context.Background(), now); err != nil {
+				s.logger.Warn("failed to send dynamic sampler summaries", zap.Error(err))
+			}
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// flush sends a summary log record for every key counted since the last flush and starts counting afresh.
+func (s *summarizer) flush(ctx context.Context, now time.Time) error {
+	s.mu.Lock()
+	counts := s.counts
+	s.counts = make(map[summaryKey]*summaryCounts, len(counts))
+	s.mu.Unlock()
+
+	if len(counts) == 0 {
+		return nil
+	}
+	return s.next.ConsumeLogs(ctx, summaryLogs(counts, now))
+}
+
+// summaryLogs builds one summary log record per key.
+func summaryLogs(counts map[summaryKey]*summaryCounts, now time.Time) plog.Logs {
+	logs := plog.NewLogs()
+	sl := logs.ResourceLogs().AppendEmpty().ScopeLogs().AppendEmpty()
+	sl.Scope().SetName(metadata.ScopeName)
+	records := sl.LogRecords()
+	records.EnsureCapacity(len(counts))
+
+	timestamp := pcommon.NewTimestampFromTime(now)
+	for k, c := range counts {
+		kept := c.seen - c.dropped
+
+		lr := records.AppendEmpty()
+		lr.SetTimestamp(timestamp)
+		lr.SetObservedTimestamp(timestamp)
+		lr.SetSeverityNumber(plog.SeverityNumberInfo)
+		lr.Body().SetStr(fmt.Sprintf("dynamic sampler kept %d and dropped %d of %d records", kept, c.dropped, c.seen))
+
+		attrs := lr.Attributes()
+		attrs.PutBool(summaryAttribute, true)
+		if k.sampler != "" {
+			attrs.PutStr(summarySamplerAttribute, k.sampler)
+		}
+		if k.rule != "" {
+			attrs.PutStr(summaryRuleAttribute, k.rule)
+		}
+		if k.key != "" {
+			attrs.PutStr(summaryKeyAttribute, k.key)
+		}
+		attrs.PutInt(summarySeenAttribute, c.seen)
+		attrs.PutInt(summaryKeptAttribute, kept)
+		attrs.PutInt(summaryDroppedAttribute, c.dropped)
+		if kept > 0 {
+			attrs.PutDouble(summaryEffectiveSampleRateAttribute, float64(c.seen)/float64(kept))
+		}
+	}
+	return logs
+}
@@ -0,0 +1,94 @@
+package dynamicsamplingprocessor
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/collector/consumer/consumertest"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/processor/processortest"
+)
+
+func TestLogsProcessorSummaries(t *testing.T) {
+	cfg := createDefaultConfig().(*Config)
+	cfg.Sampler = StaticSampler
+	cfg.KeyFields = []string{"key1"}
+	cfg.Static = StaticConfig{Default: 1}
+	cfg.Rules = []RuleConfig{{
+		Name:       "health checks",
+		Conditions: []string{`attributes["http.route"] == "/health"`},
+		Action:     RuleActionDrop,
+	}}
+	cfg.Summaries = SummariesConfig{Enabled: true, Interval: time.Hour}
+
+	sink := new(consumertest.LogsSink)
+	lp, err := NewFactory().CreateLogs(context.Background(), processortest.NewNopSettings(typ), cfg, sink)
+	require.NoError(t, err)
+	require.NoError(t, lp.Start(context.Background(), componenttest.NewNopHost()))
+
+	logs := plog.NewLogs()
+	records := logs.ResourceLogs().AppendEmpty().ScopeLogs().AppendEmpty().LogRecords()
+	for i := 0; i < 3; i++ {
+		records.AppendEmpty().Attributes().PutStr("http.route", "/health")
+	}
+	records.AppendEmpty().Attributes().PutStr("key1", "checkout")
+
+	require.NoError(t, lp.ConsumeLogs(context.Background(), logs))
+	require.NoError(t, lp.Shutdown(context.Background()))
+
+	require.Len(t, sink.AllLogs(), 2)
+	summaries := sink.AllLogs()[1].ResourceLogs().At(0).ScopeLogs().At(0).LogRecords()
+	require.Equal(t, 2, summaries.Len())
+
+	got := map[string]map[string]any{}
+	for i := 0; i < summaries.Len(); i++ {
+		attrs := summaries.At(i).Attributes().AsRaw()
+		name, _ := attrs["sampler.rule"].(string)
+		if name == "" {
+			name, _ = attrs["sampler.name"].(string)
+		}
+		got[name] = attrs
+	}
+	assert.Equal(t, map[string]map[string]any{
+		"health checks": {
+			"sampler.summary":       true,
+			"sampler.rule":          "health checks",
+			"sampler.seen_count":    int64(3),
+			"sampler.kept_count":    int64(0),
+			"sampler.dropped_count": int64(3),
+		},
+		"default": {
+			"sampler.summary":               true,
+			"sampler.name":                  "default",
+			"sampler.key":                   "checkout",
+			"sampler.seen_count":            int64(1),
+			"sampler.kept_count":            int64(1),
+			"sampler.dropped_count":         int64(0),
+			"sampler.effective_sample_rate": float64(1),
+		},
+	}, got)
+}
+
+func TestSummarizerFlush(t *testing.T) {
+	sink := new(consumertest.LogsSink)
+	s := newSummarizer(&SummariesConfig{}, sink, nil)
+
+	for i := 0; i < 10; i++ {
+		s.record(decision{keep: i%4 == 0, sampleRate: 4, sampler: "default", key: "a"})
+	}
+	require.NoError(t, s.flush(context.Background(), time.Now()))
+
+	// nothing was counted since the last flush
+	require.NoError(t, s.flush(context.Background(), time.Now()))
+
+	require.Len(t, sink.AllLogs(), 1)
+	lr := sink.AllLogs()[0].ResourceLogs().At(0).ScopeLogs().At(0).LogRecords().At(0)
+	assert.Equal(t, "dynamic sampler kept 3 and dropped 7 of 10 records", lr.Body().Str())
+	rate, ok := lr.Attributes().Get("sampler.effective_sample_rate")
+	require.True(t, ok)
+	assert.InDelta(t, 3.33, rate.Double(), 0.01)
+}
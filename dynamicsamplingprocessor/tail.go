@@ -0,0 +1,350 @@
+package dynamicsamplingprocessor
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/collector/consumer"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.opentelemetry.io/collector/processor/processorhelper"
+	"go.opentelemetry.io/otel/metric"
+	"go.uber.org/zap"
+
+	"github.com/honeycombio/opentelemetry-collector-configs/dynamicsamplingprocessor/internal/metadata"
+)
+
+const (
+	// defaultDecisionWait is used when decision_wait is not set.
+	defaultDecisionWait = 30 * time.Second
+
+	// defaultMaxTraces is used when max_traces is not set.
+	defaultMaxTraces = 50000
+
+	// defaultMaxSpans is used when max_spans is not set.
+	defaultMaxSpans = 500000
+
+	// defaultDecisionCacheSize is used when decision_cache_size is not set.
+	defaultDecisionCacheSize = 100000
+
+	// decisionWaitTicks is the number of times per decision wait that buffered traces are checked for an expired
+	// wait, so decisions are made at most a tenth of the wait late.
+	decisionWaitTicks = 10
+)
+
+// bufferedTrace holds the spans of a trace until its sampling decision is made.
+type bufferedTrace struct {
+	traceID pcommon.TraceID
+	arrived time.Time
+	traces  ptrace.Traces
+	spans   int
+}
+
+// tailSampler buffers the spans of each trace and makes the sampling decision for the whole trace once the trace has
+// been buffered for the decision wait, so that the key can be computed from all its spans. The buffer is bounded by
+// a number of traces and spans; when a batch takes it over either limit, the traces buffered longest are decided
+// early. Decided traces are sent to the next consumer, and their decisions are remembered so that spans arriving
+// later share the decision of their trace.
+type tailSampler struct {
+	tsp       *tracesProcessor
+	next      consumer.Traces
+	wait      time.Duration
+	maxTraces int
+	maxSpans  int
+	logger    *zap.Logger
+
+	mu     sync.Mutex
+	traces map[pcommon.TraceID]*bufferedTrace
+	spans  int
+
+	// queue holds the buffered traces in the order they arrived, which is the order their decision waits end.
+	queue []*bufferedTrace
+
+	// deciding holds the spans that arrive for traces that have left the buffer but whose decisions are not
+	// remembered yet. They are released together with their trace once it is decided.
+	deciding map[pcommon.TraceID]*bufferedTrace
+
+	decisions *decisionCache
+
+	// ctx is the context the periodic decisions are sent with. It is cancelled if shutdown runs out of time.
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+	wg     sync.WaitGroup
+}
+
+func newTailSampler(tsp *tracesProcessor, cfg *TailSamplingConfig, next consumer.Traces, logger *zap.Logger) *tailSampler {
+	t := &tailSampler{
+		tsp:       tsp,
+		next:      next,
+		wait:      cfg.DecisionWait,
+		maxTraces: cfg.MaxTraces,
+		maxSpans:  cfg.MaxSpans,
+		logger:    logger,
+		traces:    make(map[pcommon.TraceID]*bufferedTrace),
+		deciding:  make(map[pcommon.TraceID]*bufferedTrace),
+	}
+	if t.wait == 0 {
+		t.wait = defaultDecisionWait
+	}
+	if t.maxTraces == 0 {
+		t.maxTraces = defaultMaxTraces
+	}
+	if t.maxSpans == 0 {
+		t.maxSpans = defaultMaxSpans
+	}
+	cacheSize := cfg.DecisionCacheSize
+	if cacheSize == 0 {
+		cacheSize = defaultDecisionCacheSize
+	}
+	t.decisions = newDecisionCache(cacheSize)
+	return t
+}
+
+// registerCallbacks registers the callbacks of the buffered traces and spans gauges.
+func (t *tailSampler) registerCallbacks(tb *metadata.TelemetryBuilder) error {
+	err := tb.RegisterProcessorDynamicSamplerTailBufferedTracesCallback(func(_ context.Context, o metric.Int64Observer) error {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		o.Observe(int64(len(t.traces)))
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return tb.RegisterProcessorDynamicSamplerTailBufferedSpansCallback(func(_ context.Context, o metric.Int64Observer) error {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		o.Observe(int64(t.spans))
+		return nil
+	})
+}
+
+// start starts deciding traces whose decision wait is over.
+func (t *tailSampler) start() {
+	t.ctx, t.cancel = context.WithCancel(context.Background())
+	t.done = make(chan struct{})
+	t.wg.Add(1)
+	go t.decideLoop()
+}
+
+// shutdown stops the periodic decisions and decides and sends all buffered traces. Periodic decisions that are still
+// being sent are cancelled once ctx is done.
+func (t *tailSampler) shutdown(ctx context.Context) {
+	if t.done != nil {
+		close(t.done)
+		stop := context.AfterFunc(ctx, t.cancel)
+		t.wg.Wait()
+		stop()
+		t.cancel()
+	}
+
+	t.mu.Lock()
+	buffered := t.pop(func(*bufferedTrace) bool { return true })
+	t.mu.Unlock()
+	t.decideAndRelease(ctx, buffered)
+}
+
+func (t *tailSampler) decideLoop() {
+	defer t.wg.Done()
+
+	ticker := time.NewTicker(max(t.wait/decisionWaitTicks, time.Millisecond))
+	defer ticker.Stop()
+	for {
+		select {
+		case now := <-ticker.C:
+			t.mu.Lock()
+			expired := t.pop(func(bt *bufferedTrace) bool { return now.Sub(bt.arrived) >= t.wait })
+			t.mu.Unlock()
+			t.decideAndRelease(t.ctx, expired)
+		case <-t.done:
+			return
+		}
+	}
+}
+
+// process buffers the spans of traces that have not been decided yet. Spans of traces that have already been decided
+// are kept or dropped according to the remembered decision and returned.
+func (t *tailSampler) process(ctx context.Context, tracesData ptrace.Traces) (ptrace.Traces, error) {
+	now := time.Now()
+	t.mu.Lock()
+	late := t.buffer(tracesData, now)
+	evicted := t.pop(func(*bufferedTrace) bool { return len(t.traces) > t.maxTraces || t.spans > t.maxSpans })
+	t.mu.Unlock()
+
+	if len(evicted) > 0 {
+		t.tsp.telemetryBuilder.ProcessorDynamicSamplerTailEvictedTraces.Add(ctx, int64(len(evicted)))
+	}
+	t.decideAndRelease(ctx, evicted)
+
+	if len(late) > 0 {
+		kept, dropped := t.tsp.applyDecisions(ctx, tracesData, late)
+		recordSampled(ctx, t.tsp.telemetryBuilder.ProcessorDynamicSamplerTailLateSpans, kept, dropped)
+	}
+	if tracesData.ResourceSpans().Len() == 0 {
+		return tracesData, processorhelper.ErrSkipProcessingData
+	}
+	return tracesData, nil
+}
+
+// buffer moves the spans of traces that have not been decided yet from tracesData to the buffer, or to deciding for
+// traces that are being decided. It returns the remembered decisions of the traces of the spans left in tracesData.
+// The caller must hold t.mu.
+func (t *tailSampler) buffer(tracesData ptrace.Traces, now time.Time) map[pcommon.TraceID]decision {
+	type scopeKey struct {
+		traceID pcommon.TraceID
+		rs, ss  int
+	}
+	late := make(map[pcommon.TraceID]decision)
+	scopes := make(map[scopeKey]ptrace.ScopeSpans)
+
+	i := -1
+	tracesData.ResourceSpans().RemoveIf(func(rs ptrace.ResourceSpans) bool {
+		i++
+		j := -1
+		rs.ScopeSpans().RemoveIf(func(ss ptrace.ScopeSpans) bool {
+			j++
+			ss.Spans().RemoveIf(func(span ptrace.Span) bool {
+				traceID := span.TraceID()
+				if decision, ok := t.decisions.get(traceID); ok {
+					late[traceID] = decision
+					return false
+				}
+
+				bt, ok := t.traces[traceID]
+				if !ok {
+					bt, ok = t.deciding[traceID]
+				}
+				if !ok {
+					bt = &bufferedTrace{traceID: traceID, arrived: now, traces: ptrace.NewTraces()}
+					t.traces[traceID] = bt
+					t.queue = append(t.queue, bt)
+				}
+				key := scopeKey{traceID: traceID, rs: i, ss: j}
+				dest, ok := scopes[key]
+				if !ok {
+					destRS := bt.traces.ResourceSpans().AppendEmpty()
+					rs.Resource().CopyTo(destRS.Resource())
+					destRS.SetSchemaUrl(rs.SchemaUrl())
+					dest = destRS.ScopeSpans().AppendEmpty()
+					ss.Scope().CopyTo(dest.Scope())
+					dest.SetSchemaUrl(ss.SchemaUrl())
+					scopes[key] = dest
+				}
+				span.MoveTo(dest.Spans().AppendEmpty())
+				bt.spans++
+				t.spans++
+				return true
+			})
+			return ss.Spans().Len() == 0
+		})
+		return rs.ScopeSpans().Len() == 0
+	})
+	return late
+}
+
+// pop removes the traces at the front of the queue for as long as done returns true for the next one, and returns
+// them. Spans arriving for the popped traces are kept in deciding until decideAndRelease remembers their decisions.
+// The caller must hold t.mu.
+func (t *tailSampler) pop(done func(bt *bufferedTrace) bool) []*bufferedTrace {
+	var popped []*bufferedTrace
+	for len(t.queue) > 0 && done(t.queue[0]) {
+		bt := t.queue[0]
+		t.queue[0] = nil
+		t.queue = t.queue[1:]
+		delete(t.traces, bt.traceID)
+		t.deciding[bt.traceID] = &bufferedTrace{traceID: bt.traceID, arrived: bt.arrived, traces: ptrace.NewTraces()}
+		t.spans -= bt.spans
+		popped = append(popped, bt)
+	}
+	return popped
+}
+
+// decideAndRelease decides the traces returned by pop, remembers their decisions and releases their spans, together
+// with the spans that arrived for them while they were being decided. The caller must not hold t.mu.
+func (t *tailSampler) decideAndRelease(ctx context.Context, popped []*bufferedTrace) {
+	if len(popped) == 0 {
+		return
+	}
+	traces, decisions := t.decide(ctx, popped)
+
+	t.mu.Lock()
+	for traceID, decision := range decisions {
+		t.decisions.add(traceID, decision)
+		late := t.deciding[traceID]
+		delete(t.deciding, traceID)
+		t.spans -= late.spans
+		late.traces.ResourceSpans().MoveAndAppendTo(traces.ResourceSpans())
+	}
+	t.mu.Unlock()
+
+	t.release(ctx, traces, decisions)
+}
+
+// decide makes the sampling decision for each of the given traces, which must have left the buffer. It returns the
+// spans of the traces together with the decisions.
+func (t *tailSampler) decide(ctx context.Context, buffered []*bufferedTrace) (ptrace.Traces, map[pcommon.TraceID]decision) {
+	traces := ptrace.NewTraces()
+	decisions := make(map[pcommon.TraceID]decision, len(buffered))
+	for _, bt := range buffered {
+		_, spans := groupSpans(bt.traces)
+		decisions[bt.traceID] = t.tsp.decideTrace(ctx, spans[bt.traceID])
+		bt.traces.ResourceSpans().MoveAndAppendTo(traces.ResourceSpans())
+	}
+	return traces, decisions
+}
+
+// release applies the decisions to the spans of the decided traces and sends the kept spans to the next consumer.
+func (t *tailSampler) release(ctx context.Context, traces ptrace.Traces, decisions map[pcommon.TraceID]decision) {
+	if len(decisions) == 0 {
+		return
+	}
+	t.tsp.applyDecisions(ctx, traces, decisions)
+	if traces.ResourceSpans().Len() == 0 {
+		return
+	}
+	if err := t.next.ConsumeTraces(ctx, traces); err != nil {
+		t.logger.Warn("failed to send tail sampled traces", zap.Error(err))
+	}
+}
+
+// decisionCache remembers the decisions of the most recently decided traces, up to size of them.
+type decisionCache struct {
+	decisions map[pcommon.TraceID]decision
+
+	// order is a ring of the remembered trace IDs, in which next is the oldest once the ring is full.
+	order []pcommon.TraceID
+	next  int
+	size  int
+}
+
+func newDecisionCache(size int) *decisionCache {
+	return &decisionCache{
+		decisions: make(map[pcommon.TraceID]decision, size),
+		order:     make([]pcommon.TraceID, 0, size),
+		size:      size,
+	}
+}
+
+// get returns the remembered decision of the trace.
+func (c *decisionCache) get(traceID pcommon.TraceID) (decision, bool) {
+	d, ok := c.decisions[traceID]
+	return d, ok
+}
+
+// add remembers the decision of the trace, forgetting the oldest decision if the cache is full.
+func (c *decisionCache) add(traceID pcommon.TraceID, d decision) {
+	if _, ok := c.decisions[traceID]; ok {
+		c.decisions[traceID] = d
+		return
+	}
+	if len(c.order) < c.size {
+		c.order = append(c.order, traceID)
+	} else {
+		delete(c.decisions, c.order[c.next])
+		c.order[c.next] = traceID
+		c.next = (c.next + 1) % c.size
+	}
+	c.decisions[traceID] = d
+}
@@ -0,0 +1,222 @@
+package dynamicsamplingprocessor
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/collector/consumer/consumertest"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.opentelemetry.io/collector/processor/processorhelper"
+	"go.opentelemetry.io/collector/processor/processortest"
+	"go.uber.org/zap"
+
+	dynsampler "github.com/honeycombio/dynsampler-go"
+)
+
+// newTestTailSampler returns a tailSampler that is not started, so traces are only decided when the buffer is full
+// or the sampler is shut down.
+func newTestTailSampler(t *testing.T, sampler dynsampler.Sampler, cfg TailSamplingConfig) (*tailSampler, *consumertest.TracesSink) {
+	tsp := newTestTracesProcessor(t, sampler)
+	sink := new(consumertest.TracesSink)
+	tsp.tail = newTailSampler(tsp, &cfg, sink, zap.NewNop())
+	return tsp.tail, sink
+}
+
+// newTestTrace returns a batch with one span per name of the given trace, the first of which is the root span.
+func newTestTrace(traceID pcommon.TraceID, names ...string) ptrace.Traces {
+	td := ptrace.NewTraces()
+	ss := td.ResourceSpans().AppendEmpty().ScopeSpans().AppendEmpty()
+	for i, name := range names {
+		parentID := pcommon.SpanID([8]byte{1})
+		if i == 0 {
+			parentID = pcommon.NewSpanIDEmpty()
+		}
+		appendSpan(ss, traceID, pcommon.SpanID([8]byte{byte(i + 1)}), parentID, name)
+		ss.Spans().At(i).SetName(name)
+	}
+	return td
+}
+
+func TestTailSamplerReleasesTraceAfterDecisionWait(t *testing.T) {
+	cfg := createDefaultConfig().(*Config)
+	cfg.GoalSampleRate = 1
+	cfg.TailSampling = TailSamplingConfig{Enabled: true, DecisionWait: 50 * time.Millisecond}
+
+	sink := new(consumertest.TracesSink)
+	tp, err := NewFactory().CreateTraces(context.Background(), processortest.NewNopSettings(typ), cfg, sink)
+	require.NoError(t, err)
+	require.NoError(t, tp.Start(context.Background(), componenttest.NewNopHost()))
+
+	traceID := pcommon.TraceID([16]byte{1})
+	require.NoError(t, tp.ConsumeTraces(context.Background(), newTestTrace(traceID, "root", "child")))
+	require.NoError(t, tp.ConsumeTraces(context.Background(), newTestTrace(traceID, "late child")))
+	assert.Zero(t, sink.SpanCount())
+
+	require.Eventually(t, func() bool { return sink.SpanCount() == 3 }, 5*time.Second, 10*time.Millisecond)
+	assert.Len(t, sink.AllTraces(), 1)
+	require.NoError(t, tp.Shutdown(context.Background()))
+}
+
+func TestTailSamplerKeyFromWholeTrace(t *testing.T) {
+	sampler := &recordingSampler{Static: dynsampler.Static{Default: 1}}
+	tail, sink := newTestTailSampler(t, sampler, TailSamplingConfig{Enabled: true})
+	tail.tsp.decider.sampler.keyFields = []string{"trace.has_error", "trace.any.db.system"}
+
+	traceID := pcommon.TraceID([16]byte{1})
+	_, err := tail.process(context.Background(), newTestTrace(traceID, "root"))
+	require.ErrorIs(t, err, processorhelper.ErrSkipProcessingData)
+
+	child := newTestTrace(traceID, "query")
+	span := child.ResourceSpans().At(0).ScopeSpans().At(0).Spans().At(0)
+	span.SetParentSpanID(pcommon.SpanID([8]byte{1}))
+	span.Status().SetCode(ptrace.StatusCodeError)
+	span.Attributes().PutStr("db.system", "postgresql")
+	_, err = tail.process(context.Background(), child)
+	require.ErrorIs(t, err, processorhelper.ErrSkipProcessingData)
+	assert.Empty(t, sampler.keys)
+
+	tail.shutdown(context.Background())
+	assert.Equal(t, []string{"true_postgresql"}, sampler.keys)
+	assert.Equal(t, 2, sink.SpanCount())
+}
+
+func TestTailSamplerEvictsOldestTraces(t *testing.T) {
+	sampler := &recordingSampler{Static: dynsampler.Static{Default: 1}}
+	tail, sink := newTestTailSampler(t, sampler, TailSamplingConfig{Enabled: true, MaxTraces: 2, MaxSpans: 3})
+
+	for i := byte(1); i <= 3; i++ {
+		_, err := tail.process(context.Background(), newTestTrace(pcommon.TraceID([16]byte{i}), "root"))
+		require.ErrorIs(t, err, processorhelper.ErrSkipProcessingData)
+	}
+	require.Len(t, sink.AllTraces(), 1)
+	assert.Equal(t, pcommon.TraceID([16]byte{1}), sink.AllTraces()[0].ResourceSpans().At(0).ScopeSpans().At(0).Spans().At(0).TraceID())
+
+	// two more spans take the buffer over max_spans, so the second trace is decided too
+	_, err := tail.process(context.Background(), newTestTrace(pcommon.TraceID([16]byte{3}), "child", "child"))
+	require.ErrorIs(t, err, processorhelper.ErrSkipProcessingData)
+	require.Len(t, sink.AllTraces(), 2)
+	assert.Equal(t, pcommon.TraceID([16]byte{2}), sink.AllTraces()[1].ResourceSpans().At(0).ScopeSpans().At(0).Spans().At(0).TraceID())
+	assert.Equal(t, 1, len(tail.traces))
+	assert.Equal(t, 3, tail.spans)
+
+	tail.shutdown(context.Background())
+	assert.Equal(t, 5, sink.SpanCount())
+	assert.Empty(t, tail.traces)
+	assert.Zero(t, tail.spans)
+}
+
+func TestTailSamplerLateSpansFollowDecision(t *testing.T) {
+	sampler := &recordingSampler{Static: dynsampler.Static{Default: 1}}
+	tail, _ := newTestTailSampler(t, sampler, TailSamplingConfig{Enabled: true})
+
+	kept := pcommon.TraceID([16]byte{1})
+	dropped := pcommon.TraceID([16]byte{2})
+	tail.decisions.add(kept, decision{keep: true, sampleRate: 4})
+	tail.decisions.add(dropped, decision{keep: false, sampleRate: 4})
+
+	td := ptrace.NewTraces()
+	ss := td.ResourceSpans().AppendEmpty().ScopeSpans().AppendEmpty()
+	appendSpan(ss, kept, pcommon.SpanID([8]byte{3}), pcommon.SpanID([8]byte{1}), "late")
+	appendSpan(ss, dropped, pcommon.SpanID([8]byte{3}), pcommon.SpanID([8]byte{1}), "late")
+	appendSpan(ss, kept, pcommon.SpanID([8]byte{2}), pcommon.SpanID([8]byte{1}), "late")
+	appendSpan(ss, dropped, pcommon.SpanID([8]byte{2}), pcommon.SpanID([8]byte{1}), "late")
+	appendSpan(ss, pcommon.TraceID([16]byte{3}), pcommon.SpanID([8]byte{1}), pcommon.NewSpanIDEmpty(), "new")
+
+	td, err := tail.process(context.Background(), td)
+	require.NoError(t, err)
+
+	spans := td.ResourceSpans().At(0).ScopeSpans().At(0).Spans()
+	require.Equal(t, 2, spans.Len())
+	for i := 0; i < spans.Len(); i++ {
+		assert.Equal(t, kept, spans.At(i).TraceID())
+		rate, ok := spans.At(i).Attributes().Get("SampleRate")
+		require.True(t, ok)
+		assert.Equal(t, int64(4), rate.Int())
+	}
+	assert.Empty(t, sampler.keys)
+	assert.Len(t, tail.traces, 1)
+
+	tail.shutdown(context.Background())
+}
+
+func TestTailSamplerSpansArrivingWhileDeciding(t *testing.T) {
+	sampler := &recordingSampler{Static: dynsampler.Static{Default: 1}}
+	tail, sink := newTestTailSampler(t, sampler, TailSamplingConfig{Enabled: true})
+
+	traceID := pcommon.TraceID([16]byte{1})
+	_, err := tail.process(context.Background(), newTestTrace(traceID, "root"))
+	require.ErrorIs(t, err, processorhelper.ErrSkipProcessingData)
+
+	tail.mu.Lock()
+	popped := tail.pop(func(*bufferedTrace) bool { return true })
+	tail.mu.Unlock()
+	require.Len(t, popped, 1)
+
+	// a span of the trace that arrives before its decision is remembered waits for that decision
+	child := newTestTrace(traceID, "late")
+	child.ResourceSpans().At(0).ScopeSpans().At(0).Spans().At(0).SetParentSpanID(pcommon.SpanID([8]byte{1}))
+	_, err = tail.process(context.Background(), child)
+	require.ErrorIs(t, err, processorhelper.ErrSkipProcessingData)
+	assert.Empty(t, tail.traces)
+	assert.Empty(t, tail.queue)
+	assert.Equal(t, 1, tail.spans)
+
+	tail.decideAndRelease(context.Background(), popped)
+	assert.Equal(t, 2, sink.SpanCount())
+	assert.Len(t, sampler.keys, 1)
+	assert.Empty(t, tail.deciding)
+	assert.Zero(t, tail.spans)
+
+	tail.shutdown(context.Background())
+}
+
+func TestTailSamplerBuffersResourceAndScope(t *testing.T) {
+	sampler := &recordingSampler{Static: dynsampler.Static{Default: 1}}
+	tail, sink := newTestTailSampler(t, sampler, TailSamplingConfig{Enabled: true})
+
+	td := ptrace.NewTraces()
+	for _, service := range []string{"frontend", "backend"} {
+		rs := td.ResourceSpans().AppendEmpty()
+		rs.Resource().Attributes().PutStr("service.name", service)
+		ss := rs.ScopeSpans().AppendEmpty()
+		ss.Scope().SetName(service + "-instrumentation")
+		appendSpan(ss, pcommon.TraceID([16]byte{1}), pcommon.SpanID([8]byte{1}), pcommon.NewSpanIDEmpty(), service)
+		appendSpan(ss, pcommon.TraceID([16]byte{2}), pcommon.SpanID([8]byte{1}), pcommon.NewSpanIDEmpty(), service)
+	}
+	_, err := tail.process(context.Background(), td)
+	require.ErrorIs(t, err, processorhelper.ErrSkipProcessingData)
+	tail.shutdown(context.Background())
+
+	require.Len(t, sink.AllTraces(), 1)
+	released := sink.AllTraces()[0].ResourceSpans()
+	require.Equal(t, 4, released.Len())
+	for i := 0; i < released.Len(); i++ {
+		service, _ := released.At(i).Resource().Attributes().Get("service.name")
+		ss := released.At(i).ScopeSpans()
+		require.Equal(t, 1, ss.Len())
+		assert.Equal(t, service.Str()+"-instrumentation", ss.At(0).Scope().Name())
+		require.Equal(t, 1, ss.At(0).Spans().Len())
+		value, _ := ss.At(0).Spans().At(0).Attributes().Get("key1")
+		assert.Equal(t, service.Str(), value.Str())
+	}
+}
+
+func TestDecisionCacheForgetsOldest(t *testing.T) {
+	c := newDecisionCache(2)
+	for i := byte(1); i <= 3; i++ {
+		c.add(pcommon.TraceID([16]byte{i}), decision{keep: true, sampleRate: int(i)})
+	}
+
+	_, ok := c.get(pcommon.TraceID([16]byte{1}))
+	assert.False(t, ok)
+	for i := byte(2); i <= 3; i++ {
+		d, ok := c.get(pcommon.TraceID([16]byte{i}))
+		assert.True(t, ok)
+		assert.Equal(t, int(i), d.sampleRate)
+	}
+}
@@ -0,0 +1,224 @@
+package dynamicsamplingprocessor
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+
+	"github.com/honeycombio/opentelemetry-collector-configs/dynamicsamplingprocessor/internal/metadata"
+)
+
+const (
+	// defaultSamplerName identifies the default sampler in telemetry.
+	defaultSamplerName = "default"
+
+	// topKeysReported is the number of keys per sampler reported by the key sample rate gauge.
+	topKeysReported = 10
+
+	// keyRatesPeriod is how long key traffic is counted before the busiest keys are chosen afresh.
+	keyRatesPeriod = time.Minute
+
+	// maxTrackedKeys bounds the number of keys tracked per sampler in a period. Keys first seen after the limit
+	// is reached are not reported until the next period.
+	maxTrackedKeys = 10000
+)
+
+var (
+	sampledTrueAttr  = metric.WithAttributeSet(attribute.NewSet(attribute.Bool("sampled", true)))
+	sampledFalseAttr = metric.WithAttributeSet(attribute.NewSet(attribute.Bool("sampled", false)))
+)
+
+// keyRate is the latest sample rate of a key and the number of records seen for it in the current period.
+type keyRate struct {
+	key        string
+	count      int64
+	sampleRate int
+}
+
+// keyRates tracks the sample rate and traffic of each key for the key sample rate gauge. Counts start over every
+// keyRatesPeriod so that the gauge follows the keys that are currently busiest. Keys are spread over shards like the
+// keys of a shardedSampler, so that recording keys in different shards does not contend on one lock.
+type keyRates struct {
+	shards []keyRatesShard
+}
+
+// keyRatesShard tracks the keys of one shard of keyRates, up to maxKeys of them.
+type keyRatesShard struct {
+	mu      sync.Mutex
+	keys    map[string]*keyRate
+	period  time.Time
+	maxKeys int
+
+	// previous holds the keys of the period before the current one, or nil if no record was seen in it.
+	previous map[string]*keyRate
+}
+
+// roll starts a new period if the current one is over at now. The caller must hold shard.mu.
+func (shard *keyRatesShard) roll(now time.Time) {
+	elapsed := now.Sub(shard.period)
+	if elapsed < keyRatesPeriod {
+		return
+	}
+	shard.previous = nil
+	if elapsed < 2*keyRatesPeriod {
+		shard.previous = shard.keys
+	}
+	shard.keys = make(map[string]*keyRate, len(shard.keys))
+	shard.period = shard.period.Add(elapsed.Truncate(keyRatesPeriod))
+}
+
+// newKeyRates returns keyRates with the given number of shards. maxTrackedKeys is divided between the shards.
+func newKeyRates(shards int) *keyRates {
+	shards = max(shards, 1)
+	now := time.Now()
+	k := &keyRates{shards: make([]keyRatesShard, shards)}
+	for i := range k.shards {
+		k.shards[i].keys = make(map[string]*keyRate)
+		k.shards[i].period = now
+		k.shards[i].maxKeys = divideShards(maxTrackedKeys, shards)
+	}
+	return k
+}
+
+// record notes that a record with the given key was given sampleRate at now.
+func (k *keyRates) record(key string, sampleRate int, now time.Time) {
+	shard := &k.shards[keyShard(key, len(k.shards))]
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	shard.roll(now)
+
+	kr, ok := shard.keys[key]
+	if !ok {
+		if len(shard.keys) >= shard.maxKeys {
+			return
+		}
+		kr = &keyRate{key: key}
+		shard.keys[key] = kr
+	}
+	kr.count++
+	kr.sampleRate = sampleRate
+}
+
+// top returns the n keys with the most records in the current period, busiest first.
+func (k *keyRates) top(n int) []keyRate {
+	var rates []keyRate
+	for i := range k.shards {
+		shard := &k.shards[i]
+		shard.mu.Lock()
+		for _, kr := range shard.keys {
+			rates = append(rates, *kr)
+		}
+		shard.mu.Unlock()
+	}
+
+	sort.Slice(rates, func(i, j int) bool {
+		if rates[i].count != rates[j].count {
+			return rates[i].count > rates[j].count
+		}
+		return rates[i].key < rates[j].key
+	})
+	if len(rates) > n {
+		rates = rates[:n]
+	}
+	return rates
+}
+
+// keyStats is the traffic of a key in the current and the previous keyRatesPeriod.
+type keyStats struct {
+	Key string `json:"key"`
+
+	// SampleRate is the latest sample rate of the key.
+	SampleRate int `json:"sample_rate"`
+
+	// LastIntervalCount is the number of records seen in the previous period, and CurrentIntervalCount the number
+	// seen in the current period so far.
+	LastIntervalCount    int64 `json:"last_interval_count"`
+	CurrentIntervalCount int64 `json:"current_interval_count"`
+}
+
+// stats returns the keys seen in the current or the previous period at now, busiest in the previous period first.
+func (k *keyRates) stats(now time.Time) []keyStats {
+	var stats []keyStats
+	for i := range k.shards {
+		shard := &k.shards[i]
+		shard.mu.Lock()
+		shard.roll(now)
+		for key, kr := range shard.previous {
+			ks := keyStats{Key: key, SampleRate: kr.sampleRate, LastIntervalCount: kr.count}
+			if cur, ok := shard.keys[key]; ok {
+				ks.SampleRate = cur.sampleRate
+				ks.CurrentIntervalCount = cur.count
+			}
+			stats = append(stats, ks)
+		}
+		for key, kr := range shard.keys {
+			if _, ok := shard.previous[key]; !ok {
+				stats = append(stats, keyStats{Key: key, SampleRate: kr.sampleRate, CurrentIntervalCount: kr.count})
+			}
+		}
+		shard.mu.Unlock()
+	}
+
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].LastIntervalCount != stats[j].LastIntervalCount {
+			return stats[i].LastIntervalCount > stats[j].LastIntervalCount
+		}
+		if stats[i].CurrentIntervalCount != stats[j].CurrentIntervalCount {
+			return stats[i].CurrentIntervalCount > stats[j].CurrentIntervalCount
+		}
+		return stats[i].Key < stats[j].Key
+	})
+	return stats
+}
+
+// registerSamplerCallbacks registers the callbacks of the asynchronous sampler metrics for every sampler of d.
+func registerSamplerCallbacks[K any](tb *metadata.TelemetryBuilder, d *decider[K]) error {
+	err := tb.RegisterProcessorDynamicSamplerActiveKeysCallback(func(_ context.Context, o metric.Int64Observer) error {
+		for _, s := range d.samplers {
+			o.Observe(s.keyspaceSize(), metric.WithAttributes(attribute.String("sampler", s.name)))
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	err = tb.RegisterProcessorDynamicSamplerOverflowRecordsCallback(func(_ context.Context, o metric.Int64Observer) error {
+		for _, s := range d.samplers {
+			if s.limiter != nil {
+				o.Observe(s.overflows(), metric.WithAttributes(attribute.String("sampler", s.name)))
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return tb.RegisterProcessorDynamicSamplerKeySampleRateCallback(func(_ context.Context, o metric.Int64Observer) error {
+		for _, s := range d.samplers {
+			for _, kr := range s.rates.top(topKeysReported) {
+				o.Observe(int64(kr.sampleRate), metric.WithAttributes(
+					attribute.String("sampler", s.name),
+					attribute.String("key", kr.key),
+				))
+			}
+		}
+		return nil
+	})
+}
+
+// recordSampled adds the number of kept and dropped records to counter.
+func recordSampled(ctx context.Context, counter metric.Int64Counter, kept, dropped int64) {
+	if kept > 0 {
+		counter.Add(ctx, kept, sampledTrueAttr)
+	}
+	if dropped > 0 {
+		counter.Add(ctx, dropped, sampledFalseAttr)
+	}
+}
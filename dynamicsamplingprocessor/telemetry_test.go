@@ -0,0 +1,122 @@
+package dynamicsamplingprocessor
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/collector/consumer/consumertest"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata/metricdatatest"
+
+	"github.com/honeycombio/opentelemetry-collector-configs/dynamicsamplingprocessor/internal/metadatatest"
+)
+
+func TestKeyRatesTop(t *testing.T) {
+	rates := newKeyRates(1)
+	now := rates.shards[0].period
+	for i := 0; i < 3; i++ {
+		rates.record("busy", 10, now)
+	}
+	rates.record("quiet", 1, now)
+	rates.record("other", 2, now)
+	rates.record("other", 4, now)
+
+	expected := []keyRate{
+		{key: "busy", count: 3, sampleRate: 10},
+		{key: "other", count: 2, sampleRate: 4},
+	}
+	assert.Equal(t, expected, rates.top(2))
+	assert.Equal(t, expected, rates.top(2))
+
+	rates.record("quiet", 1, now.Add(keyRatesPeriod))
+	assert.Equal(t, []keyRate{{key: "quiet", count: 1, sampleRate: 1}}, rates.top(2))
+}
+
+func TestKeyRatesStats(t *testing.T) {
+	rates := newKeyRates(2)
+	now := rates.shards[0].period
+	for i := range rates.shards {
+		rates.shards[i].period = now
+	}
+
+	rates.record("gone", 1, now)
+	rates.record("busy", 10, now)
+	rates.record("busy", 10, now)
+	assert.Equal(t, []keyStats{
+		{Key: "busy", SampleRate: 10, CurrentIntervalCount: 2},
+		{Key: "gone", SampleRate: 1, CurrentIntervalCount: 1},
+	}, rates.stats(now))
+
+	now = now.Add(keyRatesPeriod)
+	rates.record("busy", 20, now)
+	rates.record("new", 3, now)
+	assert.Equal(t, []keyStats{
+		{Key: "busy", SampleRate: 20, LastIntervalCount: 2, CurrentIntervalCount: 1},
+		{Key: "gone", SampleRate: 1, LastIntervalCount: 1},
+		{Key: "new", SampleRate: 3, CurrentIntervalCount: 1},
+	}, rates.stats(now))
+
+	// the last interval had no records
+	assert.Empty(t, rates.stats(now.Add(3*keyRatesPeriod)))
+}
+
+func TestLogsProcessorTelemetry(t *testing.T) {
+	tt := componenttest.NewTelemetry()
+	t.Cleanup(func() { require.NoError(t, tt.Shutdown(context.Background())) })
+
+	cfg := createDefaultConfig().(*Config)
+	cfg.Sampler = StaticSampler
+	cfg.KeyFields = []string{"key1"}
+	cfg.Static = StaticConfig{Default: 1, Rates: map[string]int{"kept": 1}}
+	cfg.Rules = []RuleConfig{{
+		Name:       "drop debug",
+		Conditions: []string{`severity_text == "DEBUG"`},
+		Action:     RuleActionDrop,
+	}}
+
+	sink := new(consumertest.LogsSink)
+	lp, err := NewFactory().CreateLogs(context.Background(), metadatatest.NewSettings(tt), cfg, sink)
+	require.NoError(t, err)
+
+	logs := plog.NewLogs()
+	records := logs.ResourceLogs().AppendEmpty().ScopeLogs().AppendEmpty().LogRecords()
+	records.AppendEmpty().Attributes().PutStr("key1", "kept")
+	records.AppendEmpty().Attributes().PutStr("key1", "kept")
+	records.AppendEmpty().SetSeverityText("DEBUG")
+
+	require.NoError(t, lp.ConsumeLogs(context.Background(), logs))
+
+	metadatatest.AssertEqualProcessorDynamicSamplerCountLogsSampled(t, tt,
+		[]metricdata.DataPoint[int64]{
+			{Value: 2, Attributes: attribute.NewSet(attribute.Bool("sampled", true))},
+			{Value: 1, Attributes: attribute.NewSet(attribute.Bool("sampled", false))},
+		},
+		metricdatatest.IgnoreTimestamp())
+	metadatatest.AssertEqualProcessorDynamicSamplerSampleRate(t, tt,
+		[]metricdata.HistogramDataPoint[int64]{{
+			Count:        2,
+			Sum:          2,
+			Min:          metricdata.NewExtrema[int64](1),
+			Max:          metricdata.NewExtrema[int64](1),
+			Bounds:       []float64{1, 2, 5, 10, 25, 50, 100, 250, 500, 1000, 10000},
+			BucketCounts: []uint64{2, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0},
+		}},
+		metricdatatest.IgnoreTimestamp())
+	metadatatest.AssertEqualProcessorDynamicSamplerActiveKeys(t, tt,
+		[]metricdata.DataPoint[int64]{
+			{Value: 1, Attributes: attribute.NewSet(attribute.String("sampler", "default"))},
+		},
+		metricdatatest.IgnoreTimestamp())
+	metadatatest.AssertEqualProcessorDynamicSamplerKeySampleRate(t, tt,
+		[]metricdata.DataPoint[int64]{
+			{Value: 1, Attributes: attribute.NewSet(attribute.String("sampler", "default"), attribute.String("key", "kept"))},
+		},
+		metricdatatest.IgnoreTimestamp())
+
+	require.NoError(t, lp.Shutdown(context.Background()))
+}
@@ -0,0 +1,307 @@
+package dynamicsamplingprocessor
+
+import (
+	"context"
+	"errors"
+
+	"github.com/open-telemetry/opentelemetry-collector-contrib/pkg/ottl/contexts/ottlspan"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/consumer"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.opentelemetry.io/collector/processor"
+	"go.opentelemetry.io/collector/processor/processorhelper"
+	"go.uber.org/zap"
+
+	"github.com/honeycombio/opentelemetry-collector-configs/dynamicsamplingprocessor/internal/metadata"
+)
+
+type tracesProcessor struct {
+	decider             *decider[ottlspan.TransformContext]
+	sampleRateAttribute string
+	weighted            bool
+	countBytes          bool
+	probabilistic       bool
+	dryRun              bool
+
+	state       *stateStore[ottlspan.TransformContext]
+	budget      *budgetController
+	schedules   *scheduleController[ottlspan.TransformContext]
+	debug       *debugSignal
+	debugServer *debugServer
+
+	// tail is nil unless tail sampling is enabled.
+	tail *tailSampler
+
+	telemetryBuilder *metadata.TelemetryBuilder
+	logger           *zap.Logger
+
+	id               component.ID
+	goalSourceID     *component.ID
+	unsubscribeGoals func()
+}
+
+// newTracesProcessor returns a processor.Traces that will perform head sampling according to the given
+// configuration. All spans that share a trace ID get the same sampling decision.
+func newTracesProcessor(ctx context.Context, set processor.Settings, nextConsumer consumer.Traces, cfg *Config) (processor.Traces, error) {
+	parser, err := newSpanParser(set.TelemetrySettings)
+	if err != nil {
+		return nil, err
+	}
+
+	decider, err := newDecider(cfg, parser, set.TelemetrySettings)
+	if err != nil {
+		return nil, err
+	}
+
+	telemetryBuilder, err := metadata.NewTelemetryBuilder(set.TelemetrySettings)
+	if err != nil {
+		return nil, err
+	}
+	if err = registerSamplerCallbacks(telemetryBuilder, decider); err != nil {
+		telemetryBuilder.Shutdown()
+		return nil, err
+	}
+
+	tsp := &tracesProcessor{
+		decider:             decider,
+		sampleRateAttribute: cfg.SampleRateAttribute,
+		weighted:            cfg.WeightByUpstreamSampleRate,
+		countBytes:          cfg.ThroughputUnit == ThroughputUnitBytes,
+		probabilistic:       cfg.ProbabilitySampling,
+		dryRun:              cfg.DryRun,
+		state:               newStateStore(decider, cfg, set.ID, "traces", set.Logger),
+		schedules:           newScheduleController(decider, cfg, set.Logger),
+		debug:               newDebugSignal("traces", decider),
+		telemetryBuilder:    telemetryBuilder,
+		logger:              set.Logger,
+		id:                  set.ID,
+		goalSourceID:        cfg.GoalSourceID,
+	}
+	if tsp.sampleRateAttribute == "" {
+		tsp.sampleRateAttribute = defaultSampleRateAttribute
+	}
+	if cfg.TailSampling.Enabled {
+		tsp.tail = newTailSampler(tsp, &cfg.TailSampling, nextConsumer, set.Logger)
+		if err = tsp.tail.registerCallbacks(telemetryBuilder); err != nil {
+			telemetryBuilder.Shutdown()
+			return nil, err
+		}
+	}
+
+	// the logs and traces processors created with the same ID share one budget and one debug page
+	tsp.budget = budgetControllers.acquire(set.ID, func() *budgetController { return newBudgetController(cfg, set.Logger) })
+	tsp.debugServer = debugServers.acquire(set.ID, func() *debugServer { return newDebugServer(cfg, set.ID, set.Logger) })
+
+	proc, err := processorhelper.NewTraces(
+		ctx,
+		set,
+		cfg,
+		nextConsumer,
+		tsp.processTraces,
+		processorhelper.WithCapabilities(consumer.Capabilities{MutatesData: true}),
+		processorhelper.WithStart(tsp.start),
+		processorhelper.WithShutdown(tsp.shutdown))
+	if err != nil {
+		// the processor is never shut down if it cannot be created, so release what it holds here
+		budgetControllers.release(set.ID)
+		debugServers.release(set.ID)
+		telemetryBuilder.Shutdown()
+		return nil, err
+	}
+	return proc, nil
+}
+
+// start restores any saved sampler state, starts the samplers, subscribes to goal changes, starts following the
+// goal schedules, starts serving the debug page and starts adjusting the goals to the budget.
+func (tsp *tracesProcessor) start(ctx context.Context, host component.Host) error {
+	if err := tsp.state.start(ctx, host); err != nil {
+		return err
+	}
+	if err := tsp.decider.start(); err != nil {
+		return err
+	}
+	if tsp.tail != nil {
+		tsp.tail.start()
+	}
+	unsubscribe, err := subscribeGoals(host, tsp.goalSourceID, tsp.id, tsp.decider)
+	if err != nil {
+		return err
+	}
+	tsp.unsubscribeGoals = unsubscribe
+	tsp.schedules.start()
+	if err := tsp.debugServer.attach(tsp.debug); err != nil {
+		return err
+	}
+	return tsp.budget.attach(host, tsp.decider, UsageSource.RecordedSpans)
+}
+
+// shutdown stops serving the debug page, saves the sampler state and stops the samplers. It is also called when the
+// processor was never started, or failed to start.
+func (tsp *tracesProcessor) shutdown(ctx context.Context) error {
+	if tsp.unsubscribeGoals != nil {
+		tsp.unsubscribeGoals()
+	}
+	tsp.budget.detach(tsp.decider)
+	budgetControllers.release(tsp.id)
+	tsp.schedules.shutdown()
+	debugErr := tsp.debugServer.detach(ctx, tsp.debug)
+	debugServers.release(tsp.id)
+	if tsp.tail != nil {
+		tsp.tail.shutdown(ctx)
+	}
+	tsp.telemetryBuilder.Shutdown()
+	err := errors.Join(debugErr, tsp.state.shutdown(ctx))
+	return errors.Join(err, tsp.decider.stop())
+}
+
+func (tsp *tracesProcessor) processTraces(ctx context.Context, tracesData ptrace.Traces) (ptrace.Traces, error) {
+	if tsp.tail != nil {
+		return tsp.tail.process(ctx, tracesData)
+	}
+
+	tsp.applyDecisions(ctx, tracesData, tsp.makeDecisions(ctx, tracesData))
+	if tracesData.ResourceSpans().Len() == 0 {
+		return tracesData, processorhelper.ErrSkipProcessingData
+	}
+	return tracesData, nil
+}
+
+// applyDecisions applies the decision of the trace of each span in tracesData. Spans of dropped traces are removed
+// and spans of kept traces get the sample rate attribute, or in dry run every span is annotated with the decision.
+// It returns the number of spans kept and dropped.
+func (tsp *tracesProcessor) applyDecisions(ctx context.Context, tracesData ptrace.Traces, decisions map[pcommon.TraceID]decision) (kept, dropped int64) {
+	tracesData.ResourceSpans().RemoveIf(func(rs ptrace.ResourceSpans) bool {
+		rs.ScopeSpans().RemoveIf(func(ss ptrace.ScopeSpans) bool {
+			ss.Spans().RemoveIf(func(s ptrace.Span) bool {
+				decision := decisions[s.TraceID()]
+				if decision.keep {
+					kept++
+				} else {
+					dropped++
+				}
+
+				attrs := s.Attributes()
+				upstreamRate := upstreamSampleRate(attrs, tsp.sampleRateAttribute)
+				if tsp.dryRun {
+					annotateDryRun(attrs, decision, upstreamRate)
+					return false
+				}
+				if decision.keep {
+					if tsp.probabilistic {
+						// an upstream threshold already accounts for upstream probability sampling, so only a
+						// rate from a span without one is multiplied in
+						rate, upstream := updateTraceStateThreshold(s, decision.sampleRate)
+						if !upstream {
+							rate *= upstreamRate
+						}
+						attrs.PutInt(tsp.sampleRateAttribute, int64(rate))
+					} else {
+						attrs.PutInt(tsp.sampleRateAttribute, int64(decision.sampleRate)*int64(upstreamRate))
+					}
+				}
+				return !decision.keep
+			})
+			// Filter out empty ScopeSpans
+			return ss.Spans().Len() == 0
+		})
+		// Filter out empty ResourceSpans
+		return rs.ScopeSpans().Len() == 0
+	})
+	recordSampled(ctx, tsp.telemetryBuilder.ProcessorDynamicSamplerCountSpansSampled, kept, dropped)
+	tsp.debug.record(kept, dropped)
+	return kept, dropped
+}
+
+// makeDecisions computes one sampling decision per trace ID in the batch, from the spans of the trace in the batch.
+func (tsp *tracesProcessor) makeDecisions(ctx context.Context, tracesData ptrace.Traces) map[pcommon.TraceID]decision {
+	traceIDs, traces := groupSpans(tracesData)
+	decisions := make(map[pcommon.TraceID]decision, len(traceIDs))
+	for _, traceID := range traceIDs {
+		decisions[traceID] = tsp.decideTrace(ctx, traces[traceID])
+	}
+	return decisions
+}
+
+// decideTrace computes the sampling decision for a trace. The key, and the upstream sample rate used as the weight,
+// are taken from the key span of the trace, except for the trace fields, which are computed from all its spans.
+// When counting bytes, a trace counts as the size of its spans.
+func (tsp *tracesProcessor) decideTrace(ctx context.Context, t *traceSpans) decision {
+	keySpan := t.keySpan()
+	tCtx := ottlspan.NewTransformContext(keySpan.span, keySpan.scope, keySpan.resource, keySpan.ss, keySpan.rs)
+	count := 1
+	if tsp.countBytes {
+		count = 0
+		for _, s := range t.spans {
+			count += spanSize(s.span)
+		}
+	}
+	if tsp.weighted {
+		count *= upstreamSampleRate(keySpan.span.Attributes(), tsp.sampleRateAttribute)
+	}
+
+	decision := tsp.decider.decide(ctx, tCtx, traceFieldLookup(t), count)
+	if tsp.probabilistic {
+		decision = probabilityDecision(decision, keySpan.span)
+	}
+	if decision.sampleRate > 0 {
+		tsp.telemetryBuilder.ProcessorDynamicSamplerSampleRate.Record(ctx, int64(decision.sampleRate))
+	}
+	return decision
+}
+
+// spanContext is a span together with the scope and resource it belongs to.
+type spanContext struct {
+	span     ptrace.Span
+	scope    pcommon.InstrumentationScope
+	resource pcommon.Resource
+	ss       ptrace.ScopeSpans
+	rs       ptrace.ResourceSpans
+}
+
+// traceSpans are the spans of one trace that a sampling decision is made for. The key span is the root span of the
+// trace, or the first span if the root span is not present.
+type traceSpans struct {
+	spans []spanContext
+	key   int
+}
+
+// add adds a span to the trace.
+func (t *traceSpans) add(s spanContext) {
+	if len(t.spans) > 0 && s.span.ParentSpanID().IsEmpty() {
+		t.key = len(t.spans)
+	}
+	t.spans = append(t.spans, s)
+}
+
+// keySpan returns the span the key of the trace is taken from.
+func (t *traceSpans) keySpan() spanContext {
+	return t.spans[t.key]
+}
+
+// groupSpans groups the spans in tracesData by trace ID. It returns the trace IDs in the order they first appear.
+func groupSpans(tracesData ptrace.Traces) ([]pcommon.TraceID, map[pcommon.TraceID]*traceSpans) {
+	var traceIDs []pcommon.TraceID
+	traces := make(map[pcommon.TraceID]*traceSpans)
+
+	rss := tracesData.ResourceSpans()
+	for i := 0; i < rss.Len(); i++ {
+		rs := rss.At(i)
+		sss := rs.ScopeSpans()
+		for j := 0; j < sss.Len(); j++ {
+			ss := sss.At(j)
+			spans := ss.Spans()
+			for k := 0; k < spans.Len(); k++ {
+				span := spans.At(k)
+				t, ok := traces[span.TraceID()]
+				if !ok {
+					t = &traceSpans{}
+					traces[span.TraceID()] = t
+					traceIDs = append(traceIDs, span.TraceID())
+				}
+				t.add(spanContext{span: span, scope: ss.Scope(), resource: rs.Resource(), ss: ss, rs: rs})
+			}
+		}
+	}
+	return traceIDs, traces
+}
@@ -0,0 +1,165 @@
+package dynamicsamplingprocessor
+
+import (
+	"context"
+	"testing"
+
+	"github.com/open-telemetry/opentelemetry-collector-contrib/pkg/ottl/contexts/ottlspan"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/collector/consumer/consumertest"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.opentelemetry.io/collector/processor/processortest"
+
+	dynsampler "github.com/honeycombio/dynsampler-go"
+
+	"github.com/honeycombio/opentelemetry-collector-configs/dynamicsamplingprocessor/internal/metadata"
+)
+
+// recordingSampler is a static sampler that remembers the keys and counts it was asked about.
+type recordingSampler struct {
+	dynsampler.Static
+	keys   []string
+	counts []int
+}
+
+func (r *recordingSampler) GetSampleRate(key string) int {
+	return r.GetSampleRateMulti(key, 1)
+}
+
+func (r *recordingSampler) GetSampleRateMulti(key string, count int) int {
+	r.keys = append(r.keys, key)
+	r.counts = append(r.counts, count)
+	return r.Static.GetSampleRateMulti(key, count)
+}
+
+// newTestTracesProcessor returns a tracesProcessor that samples on key1 with the given sampler.
+func newTestTracesProcessor(t *testing.T, sampler dynsampler.Sampler) *tracesProcessor {
+	telemetryBuilder, err := metadata.NewTelemetryBuilder(componenttest.NewNopTelemetrySettings())
+	require.NoError(t, err)
+
+	return &tracesProcessor{
+		decider: &decider[ottlspan.TransformContext]{
+			sampler: &keyedSampler[ottlspan.TransformContext]{
+				sampler:   sampler,
+				keyFields: []string{"key1"},
+			},
+		},
+		sampleRateAttribute: defaultSampleRateAttribute,
+		telemetryBuilder:    telemetryBuilder,
+	}
+}
+
+func appendSpan(ss ptrace.ScopeSpans, traceID pcommon.TraceID, spanID pcommon.SpanID, parentID pcommon.SpanID, value string) {
+	span := ss.Spans().AppendEmpty()
+	span.SetTraceID(traceID)
+	span.SetSpanID(spanID)
+	span.SetParentSpanID(parentID)
+	span.Attributes().PutStr("key1", value)
+}
+
+func TestTracesProcessorKeepsWholeTrace(t *testing.T) {
+	cfg := createDefaultConfig().(*Config)
+	cfg.GoalSampleRate = 1
+
+	sink := new(consumertest.TracesSink)
+	tp, err := NewFactory().CreateTraces(context.Background(), processortest.NewNopSettings(typ), cfg, sink)
+	require.NoError(t, err)
+	require.NoError(t, tp.Start(context.Background(), componenttest.NewNopHost()))
+
+	td := ptrace.NewTraces()
+	ss := td.ResourceSpans().AppendEmpty().ScopeSpans().AppendEmpty()
+	traceID := pcommon.TraceID([16]byte{1})
+	appendSpan(ss, traceID, pcommon.SpanID([8]byte{1}), pcommon.NewSpanIDEmpty(), "root")
+	appendSpan(ss, traceID, pcommon.SpanID([8]byte{2}), pcommon.SpanID([8]byte{1}), "child")
+
+	require.NoError(t, tp.ConsumeTraces(context.Background(), td))
+	require.NoError(t, tp.Shutdown(context.Background()))
+
+	require.Len(t, sink.AllTraces(), 1)
+	spans := sink.AllTraces()[0].ResourceSpans().At(0).ScopeSpans().At(0).Spans()
+	require.Equal(t, 2, spans.Len())
+	for i := 0; i < spans.Len(); i++ {
+		rate, ok := spans.At(i).Attributes().Get("SampleRate")
+		require.True(t, ok)
+		assert.Equal(t, int64(1), rate.Int())
+	}
+}
+
+func TestTracesProcessorKeyFromRootSpan(t *testing.T) {
+	sampler := &recordingSampler{Static: dynsampler.Static{Default: 1}}
+	tsp := newTestTracesProcessor(t, sampler)
+
+	td := ptrace.NewTraces()
+	ss := td.ResourceSpans().AppendEmpty().ScopeSpans().AppendEmpty()
+
+	// root span arrives after its child
+	withRoot := pcommon.TraceID([16]byte{1})
+	appendSpan(ss, withRoot, pcommon.SpanID([8]byte{2}), pcommon.SpanID([8]byte{1}), "child")
+	appendSpan(ss, withRoot, pcommon.SpanID([8]byte{1}), pcommon.NewSpanIDEmpty(), "root")
+
+	// root span is not part of this batch
+	withoutRoot := pcommon.TraceID([16]byte{2})
+	appendSpan(ss, withoutRoot, pcommon.SpanID([8]byte{4}), pcommon.SpanID([8]byte{3}), "first")
+	appendSpan(ss, withoutRoot, pcommon.SpanID([8]byte{5}), pcommon.SpanID([8]byte{3}), "second")
+
+	decisions := tsp.makeDecisions(context.Background(), td)
+
+	assert.Equal(t, []string{"root", "first"}, sampler.keys)
+	assert.Len(t, decisions, 2)
+	assert.True(t, decisions[withRoot].keep)
+	assert.True(t, decisions[withoutRoot].keep)
+}
+
+func TestTracesProcessorWeightByUpstreamSampleRate(t *testing.T) {
+	sampler := &recordingSampler{Static: dynsampler.Static{Default: 2}}
+	tsp := newTestTracesProcessor(t, sampler)
+	tsp.weighted = true
+
+	td := ptrace.NewTraces()
+	ss := td.ResourceSpans().AppendEmpty().ScopeSpans().AppendEmpty()
+	traceID := pcommon.TraceID([16]byte{1})
+	appendSpan(ss, traceID, pcommon.SpanID([8]byte{1}), pcommon.NewSpanIDEmpty(), "root")
+	appendSpan(ss, traceID, pcommon.SpanID([8]byte{2}), pcommon.SpanID([8]byte{1}), "child")
+	ss.Spans().At(0).Attributes().PutInt("SampleRate", 10)
+	ss.Spans().At(1).Attributes().PutInt("SampleRate", 3)
+
+	decisions := tsp.makeDecisions(context.Background(), td)
+	assert.Equal(t, []int{10}, sampler.counts)
+	assert.Equal(t, 2, decisions[traceID].sampleRate)
+}
+
+func TestTracesProcessorProbabilitySampleRate(t *testing.T) {
+	tsp := newTestTracesProcessor(t, &dynsampler.Static{Default: 4})
+	tsp.probabilistic = true
+
+	td := ptrace.NewTraces()
+	ss := td.ResourceSpans().AppendEmpty().ScopeSpans().AppendEmpty()
+	stricter := pcommon.TraceID([16]byte{1, 9: 0xf0})
+	appendSpan(ss, stricter, pcommon.SpanID([8]byte{1}), pcommon.NewSpanIDEmpty(), "root")
+	ss.Spans().At(0).TraceState().FromRaw("ot=th:e")
+	ss.Spans().At(0).Attributes().PutInt("SampleRate", 8)
+	unsampled := pcommon.TraceID([16]byte{2, 9: 0xf0})
+	appendSpan(ss, unsampled, pcommon.SpanID([8]byte{2}), pcommon.NewSpanIDEmpty(), "root")
+	// sampled upstream by a sampler that writes no threshold
+	upstreamRate := pcommon.TraceID([16]byte{3, 9: 0xf0})
+	appendSpan(ss, upstreamRate, pcommon.SpanID([8]byte{3}), pcommon.NewSpanIDEmpty(), "root")
+	ss.Spans().At(2).Attributes().PutInt("SampleRate", 5)
+
+	kept, dropped := tsp.applyDecisions(context.Background(), td, tsp.makeDecisions(context.Background(), td))
+	assert.Equal(t, int64(3), kept)
+	assert.Equal(t, int64(0), dropped)
+
+	spans := td.ResourceSpans().At(0).ScopeSpans().At(0).Spans()
+	rate, _ := spans.At(0).Attributes().Get("SampleRate")
+	assert.Equal(t, int64(8), rate.Int())
+	assert.Equal(t, "ot=th:e", spans.At(0).TraceState().AsRaw())
+	rate, _ = spans.At(1).Attributes().Get("SampleRate")
+	assert.Equal(t, int64(4), rate.Int())
+	assert.Equal(t, "ot=th:c", spans.At(1).TraceState().AsRaw())
+	rate, _ = spans.At(2).Attributes().Get("SampleRate")
+	assert.Equal(t, int64(20), rate.Int())
+	assert.Equal(t, "ot=th:c", spans.At(2).TraceState().AsRaw())
+}
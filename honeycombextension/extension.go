@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/honeycombio/opentelemetry-collector-configs/usageprocessor"
@@ -72,6 +73,11 @@ type honeycombExtension struct {
 	bytesReceivedMux sync.Mutex
 	done             chan struct{}
 
+	// recordedSpans and recordedLogRecords count the spans and log records recorded since the extension was
+	// created. Unlike usage, they are not reset when usage is reported.
+	recordedSpans      atomic.Int64
+	recordedLogRecords atomic.Int64
+
 	telemetryHandler opampcustommessages.CustomCapabilityHandler
 
 	telemetryBuilder *metadata.TelemetryBuilder
@@ -143,6 +149,7 @@ func (h *honeycombExtension) RecordTracesUsage(td ptrace.Traces) {
 	h.usage[traces].bytes += int64(size)
 	h.usage[traces].count += int64(td.SpanCount())
 	h.bytesReceivedMux.Unlock()
+	h.recordedSpans.Add(int64(td.SpanCount()))
 }
 
 func (h *honeycombExtension) RecordMetricsUsage(md pmetric.Metrics) {
@@ -171,6 +178,19 @@ func (h *honeycombExtension) RecordLogsUsage(ld plog.Logs) {
 	h.usage[logs].bytes += int64(size)
 	h.usage[logs].count += int64(ld.LogRecordCount())
 	h.bytesReceivedMux.Unlock()
+	h.recordedLogRecords.Add(int64(ld.LogRecordCount()))
+}
+
+// RecordedSpans returns the number of spans recorded since the extension was created. With RecordedLogRecords, it
+// implements the UsageSource interface of the dynamic sampling processor, which adjusts its sampling goals to keep
+// the usage of the signals it samples within an event budget.
+func (h *honeycombExtension) RecordedSpans() int64 {
+	return h.recordedSpans.Load()
+}
+
+// RecordedLogRecords returns the number of log records recorded since the extension was created.
+func (h *honeycombExtension) RecordedLogRecords() int64 {
+	return h.recordedLogRecords.Load()
 }
 
 func (h *honeycombExtension) sendUsageReport(data []byte) (retry bool) {
@@ -92,6 +92,39 @@ func TestRecordLogsUsage(t *testing.T) {
 	require.Equal(t, int64(ld.LogRecordCount()), hnyExt.usage[logs].count)
 }
 
+func TestRecordedEvents(t *testing.T) {
+	ext, err := newHoneycombExtension(nil, extensiontest.NewNopSettings(metadata.Type))
+	require.NoError(t, err)
+	hnyExt, ok := ext.(*honeycombExtension)
+	require.True(t, ok)
+
+	td := ptrace.NewTraces()
+	spans := td.ResourceSpans().AppendEmpty().ScopeSpans().AppendEmpty().Spans()
+	spans.AppendEmpty().SetName("first")
+	spans.AppendEmpty().SetName("second")
+	hnyExt.RecordTracesUsage(td)
+
+	ld := plog.NewLogs()
+	ld.ResourceLogs().AppendEmpty().ScopeLogs().AppendEmpty().LogRecords().AppendEmpty().Body().SetStr("test")
+	hnyExt.RecordLogsUsage(ld)
+
+	md := pmetric.NewMetrics()
+	m := md.ResourceMetrics().AppendEmpty().ScopeMetrics().AppendEmpty().Metrics().AppendEmpty()
+	m.SetEmptyGauge()
+	m.Gauge().DataPoints().AppendEmpty().SetIntValue(1)
+	hnyExt.RecordMetricsUsage(md)
+
+	// metrics are not sampled by the dynamic sampler, so they are not counted
+	require.Equal(t, int64(2), hnyExt.RecordedSpans())
+	require.Equal(t, int64(1), hnyExt.RecordedLogRecords())
+
+	// recorded events are not reset by usage reports
+	_, err = hnyExt.createUsageReport()
+	require.NoError(t, err)
+	require.Equal(t, int64(2), hnyExt.RecordedSpans())
+	require.Equal(t, int64(1), hnyExt.RecordedLogRecords())
+}
+
 func Test_createUsageReport(t *testing.T) {
 	ext, err := newHoneycombExtension(nil, extensiontest.NewNopSettings(metadata.Type))
 	require.NoError(t, err)
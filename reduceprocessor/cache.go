@@ -58,6 +58,7 @@ func newCacheKey(groupBy []string, resource pcommon.Resource, scope pcommon.Inst
 
 type cacheEntry struct {
 	createdAt time.Time
+	updatedAt time.Time
 	resource  pcommon.Resource
 	scope     pcommon.InstrumentationScope
 	log       plog.LogRecord
@@ -67,8 +68,10 @@ type cacheEntry struct {
 }
 
 func newCacheEntry(resource pcommon.Resource, scope pcommon.InstrumentationScope, log plog.LogRecord) *cacheEntry {
+	now := time.Now().UTC()
 	return &cacheEntry{
-		createdAt: time.Now().UTC(),
+		createdAt: now,
+		updatedAt: now,
 		resource:  resource,
 		scope:     scope,
 		log:       log,
@@ -78,6 +81,7 @@ func newCacheEntry(resource pcommon.Resource, scope pcommon.InstrumentationScope
 }
 
 func (entry *cacheEntry) merge(mergeStrategies map[string]MergeStrategy, resource pcommon.Resource, scope pcommon.InstrumentationScope, logRecord plog.LogRecord) {
+	entry.updatedAt = time.Now().UTC()
 	entry.lastSeen = entry.log.Timestamp()
 	mergeAttributes(mergeStrategies, entry.resource.Attributes(), resource.Attributes())
 	mergeAttributes(mergeStrategies, entry.scope.Attributes(), scope.Attributes())
@@ -146,13 +150,16 @@ func mergeAttributes(mergeStrategies map[string]MergeStrategy, existingAttrs pco
 	})
 }
 
-func (entry *cacheEntry) isInvalid(maxCount int, maxAge time.Duration) bool {
+func (entry *cacheEntry) isInvalid(maxCount int, maxAge time.Duration, idleTimeout time.Duration) bool {
 	if entry.count >= maxCount {
 		return true
 	}
 	if maxAge > 0 && time.Since(entry.createdAt) >= maxAge {
 		return true
 	}
+	if idleTimeout > 0 && time.Since(entry.updatedAt) >= idleTimeout {
+		return true
+	}
 	return false
 }
 
@@ -2,6 +2,7 @@ package reduceprocessor
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 )
@@ -14,3 +15,13 @@ func TestEmptyGroupByReturnsError(t *testing.T) {
 	require.Error(t, err)
 	require.Equal(t, "group_by must contain at least one attribute name", err.Error())
 }
+
+func TestNegativeReduceTimeoutReturnsError(t *testing.T) {
+	cfg := &Config{
+		GroupBy:       []string{"partition_id"},
+		ReduceTimeout: -time.Second,
+	}
+	err := cfg.Validate()
+	require.Error(t, err)
+	require.Equal(t, "reduce_timeout must not be negative", err.Error())
+}
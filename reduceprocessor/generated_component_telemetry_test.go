@@ -14,6 +14,8 @@ import (
 	"go.opentelemetry.io/collector/component"
 	"go.opentelemetry.io/collector/processor"
 	"go.opentelemetry.io/collector/processor/processortest"
+
+	"github.com/honeycombio/opentelemetry-collector-configs/reduceprocessor/internal/metadata"
 )
 
 type componentTestTelemetry struct {
@@ -22,7 +24,7 @@ type componentTestTelemetry struct {
 }
 
 func (tt *componentTestTelemetry) NewSettings() processor.Settings {
-	settings := processortest.NewNopSettings()
+	settings := processortest.NewNopSettings(metadata.Type)
 	settings.MeterProvider = tt.meterProvider
 	settings.ID = component.NewID(component.MustNewType("reduce"))
 
@@ -15,6 +15,14 @@ import (
 	"github.com/honeycombio/opentelemetry-collector-configs/reduceprocessor/internal/metadata"
 )
 
+const (
+	// exportChecksPerTimeout is the number of times the cache is checked for complete entries per timeout.
+	exportChecksPerTimeout = 10
+
+	// minExportInterval bounds how often the cache is checked for complete entries.
+	minExportInterval = 100 * time.Millisecond
+)
+
 type reduceProcessor struct {
 	telemetryBuilder *metadata.TelemetryBuilder
 	nextConsumer     consumer.Logs
@@ -58,12 +66,17 @@ func (p *reduceProcessor) Start(ctx context.Context, _ component.Host) error {
 	return nil
 }
 
-// handleExportInterval sends metrics at the configured interval.
+// handleExportInterval checks the cache for complete entries at the export interval and sends them.
 func (p *reduceProcessor) handleExportInterval(ctx context.Context) {
 	defer p.wg.Done()
 
-	ticker := time.NewTicker(p.config.MaxReduceTimeout)
-	defer ticker.Stop()
+	// without any timeout, entries are only completed by max_reduce_count or shutdown
+	var tick <-chan time.Time
+	if interval := p.exportInterval(); interval > 0 {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
 
 	for {
 		select {
@@ -74,19 +87,33 @@ func (p *reduceProcessor) handleExportInterval(ctx context.Context) {
 				p.logger.Error("context error", zap.Error(err))
 			}
 			return
-		case <-ticker.C:
+		case <-tick:
 			p.exportLogs()
 		}
 	}
 }
 
+// exportInterval returns how often the cache is checked for complete entries. Entries are checked exportChecksPerTimeout
+// times per shortest timeout, so they are sent at most a fraction of the timeout after they complete. It returns 0 if no
+// timeout is set.
+func (p *reduceProcessor) exportInterval() time.Duration {
+	timeout := p.config.MaxReduceTimeout
+	if p.config.ReduceTimeout > 0 && (timeout <= 0 || p.config.ReduceTimeout < timeout) {
+		timeout = p.config.ReduceTimeout
+	}
+	if timeout <= 0 {
+		return 0
+	}
+	return max(timeout/exportChecksPerTimeout, minExportInterval)
+}
+
 // exportLogs exports the logs to the next consumer.
 func (p *reduceProcessor) exportLogs() {
 	p.mux.Lock()
 	defer p.mux.Unlock()
 
 	for k, entry := range p.cache {
-		if entry.isInvalid(p.config.MaxReduceCount, p.config.MaxReduceTimeout) {
+		if entry.isInvalid(p.config.MaxReduceCount, p.config.MaxReduceTimeout, p.config.ReduceTimeout) {
 			p.evictEntry(k, entry)
 		}
 	}
@@ -159,7 +186,7 @@ func (p *reduceProcessor) ConsumeLogs(ctx context.Context, ld plog.Logs) error {
 					entry = newCacheEntry(resource, scope, logRecord)
 				} else {
 					// check if the existing entry is still valid
-					if entry.isInvalid(p.config.MaxReduceCount, p.config.MaxReduceTimeout) {
+					if entry.isInvalid(p.config.MaxReduceCount, p.config.MaxReduceTimeout, p.config.ReduceTimeout) {
 						// not valid, remove it from the cache which triggers onEvict and sends it to the next consumer
 						p.evictEntry(key, entry)
 
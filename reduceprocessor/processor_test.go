@@ -8,11 +8,14 @@ import (
 	"time"
 
 	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component/componenttest"
 	"go.opentelemetry.io/collector/consumer/consumertest"
 	"go.opentelemetry.io/collector/processor/processortest"
 
 	"github.com/open-telemetry/opentelemetry-collector-contrib/pkg/golden"
 	"github.com/open-telemetry/opentelemetry-collector-contrib/pkg/pdatatest/plogtest"
+
+	"github.com/honeycombio/opentelemetry-collector-configs/reduceprocessor/internal/metadata"
 )
 
 func TestProcessLogsDeduplicate(t *testing.T) {
@@ -88,7 +91,7 @@ func TestProcessLogsDeduplicate(t *testing.T) {
 			oCfg.ReduceCountAttribute = "meta.merge_count"
 
 			sink := new(consumertest.LogsSink)
-			p, err := factory.CreateLogsProcessor(context.Background(), processortest.NewNopSettings(), oCfg, sink)
+			p, err := factory.CreateLogs(context.Background(), processortest.NewNopSettings(metadata.Type), oCfg, sink)
 			require.NoError(t, err)
 
 			input, err := golden.ReadLogs(filepath.Join("testdata", tc.inputFile))
@@ -120,7 +123,7 @@ func TestMaxMergeCountSendsLogsRecord(t *testing.T) {
 	cfg.MaxReduceCount = 1
 
 	sink := new(consumertest.LogsSink)
-	p, err := factory.CreateLogsProcessor(context.Background(), processortest.NewNopSettings(), cfg, sink)
+	p, err := factory.CreateLogs(context.Background(), processortest.NewNopSettings(metadata.Type), cfg, sink)
 	require.NoError(t, err)
 
 	input, err := golden.ReadLogs(filepath.Join("testdata", "max-merge.yaml"))
@@ -150,7 +153,7 @@ func TestFirstLastSeenAttributes(t *testing.T) {
 	cfg.LastSeenAttribute = "meta.last_seen"
 
 	sink := new(consumertest.LogsSink)
-	p, err := factory.CreateLogsProcessor(context.Background(), processortest.NewNopSettings(), cfg, sink)
+	p, err := factory.CreateLogs(context.Background(), processortest.NewNopSettings(metadata.Type), cfg, sink)
 	require.NoError(t, err)
 
 	input, err := golden.ReadLogs(filepath.Join("testdata", "first-last-seen.yaml"))
@@ -174,12 +177,14 @@ func TestFirstLastSeenAttributes(t *testing.T) {
 
 func TestReduceStateShouldEvict(t *testing.T) {
 	testCases := []struct {
-		name      string
-		count     int
-		createdAt time.Time
-		maxCount  int
-		maxAge    time.Duration
-		expected  bool
+		name        string
+		count       int
+		createdAt   time.Time
+		updatedAt   time.Time
+		maxCount    int
+		maxAge      time.Duration
+		idleTimeout time.Duration
+		expected    bool
 	}{
 		{
 			name:     "returns true when count is greater than max count",
@@ -215,6 +220,26 @@ func TestReduceStateShouldEvict(t *testing.T) {
 			maxAge:    1 * time.Second,
 			expected:  false,
 		},
+		{
+			name:        "returns true when idle timeout is set and state was last updated before idle timeout",
+			count:       1,
+			maxCount:    2,
+			createdAt:   time.Now().Add(-2 * time.Second),
+			updatedAt:   time.Now().Add(-2 * time.Second),
+			maxAge:      time.Minute,
+			idleTimeout: 1 * time.Second,
+			expected:    true,
+		},
+		{
+			name:        "returns false when idle timeout is set and state was updated within idle timeout",
+			count:       1,
+			maxCount:    2,
+			createdAt:   time.Now().Add(-2 * time.Second),
+			updatedAt:   time.Now(),
+			maxAge:      time.Minute,
+			idleTimeout: 1 * time.Second,
+			expected:    false,
+		},
 	}
 
 	for _, tc := range testCases {
@@ -222,8 +247,80 @@ func TestReduceStateShouldEvict(t *testing.T) {
 			state := cacheEntry{
 				count:     tc.count,
 				createdAt: tc.createdAt,
+				updatedAt: tc.updatedAt,
 			}
-			require.Equal(t, tc.expected, state.isInvalid(tc.maxCount, tc.maxAge))
+			require.Equal(t, tc.expected, state.isInvalid(tc.maxCount, tc.maxAge, tc.idleTimeout))
+		})
+	}
+}
+
+func TestReduceTimeoutSendsIdleLogsRecord(t *testing.T) {
+	factory := NewFactory()
+	cfg := factory.CreateDefaultConfig().(*Config)
+	cfg.GroupBy = []string{"partition_id"}
+	cfg.ReduceCountAttribute = "meta.merge_count"
+	cfg.ReduceTimeout = 200 * time.Millisecond
+
+	sink := new(consumertest.LogsSink)
+	p, err := factory.CreateLogs(context.Background(), processortest.NewNopSettings(metadata.Type), cfg, sink)
+	require.NoError(t, err)
+	require.NoError(t, p.Start(context.Background(), componenttest.NewNopHost()))
+
+	input, err := golden.ReadLogs(filepath.Join("testdata", "merge.yaml"))
+	require.NoError(t, err)
+	expected, err := golden.ReadLogs(filepath.Join("testdata", "merge-first-expected.yaml"))
+	require.NoError(t, err)
+
+	require.NoError(t, p.ConsumeLogs(context.Background(), input))
+	require.Empty(t, sink.AllLogs())
+
+	// the entry is sent once no record has been merged into it for reduce_timeout, long before max_reduce_timeout
+	require.Eventually(t, func() bool { return len(sink.AllLogs()) == 1 }, 5*time.Second, 10*time.Millisecond)
+	require.NoError(t, plogtest.CompareLogs(expected, sink.AllLogs()[0]))
+
+	require.NoError(t, p.Shutdown(context.Background()))
+	require.Len(t, sink.AllLogs(), 1)
+}
+
+func TestExportInterval(t *testing.T) {
+	testCases := []struct {
+		name             string
+		reduceTimeout    time.Duration
+		maxReduceTimeout time.Duration
+		expected         time.Duration
+	}{
+		{
+			name:             "uses reduce timeout when it is the shortest",
+			reduceTimeout:    10 * time.Second,
+			maxReduceTimeout: 60 * time.Second,
+			expected:         1 * time.Second,
+		},
+		{
+			name:             "uses max reduce timeout when it is the shortest",
+			reduceTimeout:    60 * time.Second,
+			maxReduceTimeout: 20 * time.Second,
+			expected:         2 * time.Second,
+		},
+		{
+			name:             "uses max reduce timeout when reduce timeout is not set",
+			maxReduceTimeout: 30 * time.Second,
+			expected:         3 * time.Second,
+		},
+		{
+			name:          "is bounded by the minimum export interval",
+			reduceTimeout: 10 * time.Millisecond,
+			expected:      minExportInterval,
+		},
+		{
+			name:     "returns 0 when no timeout is set",
+			expected: 0,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			p := &reduceProcessor{config: &Config{ReduceTimeout: tc.reduceTimeout, MaxReduceTimeout: tc.maxReduceTimeout}}
+			require.Equal(t, tc.expected, p.exportInterval())
 		})
 	}
 }